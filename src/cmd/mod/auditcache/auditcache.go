@@ -0,0 +1,131 @@
+package auditcachecmd
+
+import (
+	"path/filepath"
+
+	rootcmd "github.com/illikainen/gofer/src/cmd/root"
+	"github.com/illikainen/gofer/src/mod"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var options struct {
+	*rootcmd.Options
+	quarantine string
+	workspace  string
+	auto       bool
+	ws         *mod.Workspace
+}
+
+var command = &cobra.Command{
+	Use:   "audit-cache [flags] [<go.sum>...]",
+	Short: "Find GOPATH module cache entries that aren't verified by the specified go.sum file(s)",
+	Long: "Find GOPATH module cache entries that aren't verified by the specified go.sum file(s).\n\n" +
+		"Every downloaded and extracted module in GOPATH is classified as verified, " +
+		"unreferenced (not in any of the go.sum files) or mismatching (referenced " +
+		"but with different content).",
+	PreRunE: preRun,
+	RunE:    run,
+}
+
+func Command(opts *rootcmd.Options) *cobra.Command {
+	options.Options = opts
+	return command
+}
+
+func init() {
+	flags := command.Flags()
+
+	flags.StringVarP(&options.quarantine, "quarantine", "", "",
+		"Move unreferenced and mismatching entries to this directory")
+	flags.StringVarP(&options.workspace, "workspace", "w", "",
+		"Include the go.sum files of the go.work workspace in this directory")
+	flags.BoolVarP(&options.auto, "auto", "", false,
+		"Discover the go.sum files of the current module, repository and workspace")
+}
+
+func preRun(_ *cobra.Command, args []string) error {
+	ws, err := mod.ResolveSumFiles(&mod.ResolveOptions{
+		SumFiles:  args,
+		Workspace: options.workspace,
+		Auto:      options.auto,
+		Log:       log.StandardLogger(),
+	})
+	if err != nil {
+		return err
+	}
+	options.ws = ws
+
+	err = options.Sandbox.AddReadOnlyPath(ws.Paths()...)
+	if err != nil {
+		return err
+	}
+
+	if options.quarantine != "" {
+		err = options.Sandbox.AddReadWritePath(options.quarantine)
+		if err != nil {
+			return err
+		}
+	}
+
+	return options.Sandbox.Confine()
+}
+
+func run(cmd *cobra.Command, _ []string) error {
+	cmd.SilenceUsage = true
+
+	sum, err := mod.ReadGoSum(&mod.SumOptions{
+		SumFiles: options.ws.SumFiles,
+		SigPath:  filepath.Join(options.Config.CacheDir, "mod"),
+		GoPath:   options.GoPath,
+		Origins:  options.Config.Origins,
+		Local:    options.ws.Local,
+		Log:      log.StandardLogger(),
+	})
+	if err != nil {
+		return err
+	}
+
+	entries, err := sum.AuditCache()
+	if err != nil {
+		return err
+	}
+
+	counts := map[string]int{}
+	for _, entry := range entries {
+		counts[entry.Status]++
+		if entry.Suspicious() {
+			if entry.Reason != "" {
+				log.Warnf("%s: %s: %s", entry, entry.Status, entry.Reason)
+			} else {
+				log.Warnf("%s: %s", entry, entry.Status)
+			}
+		} else {
+			log.Debugf("%s: %s", entry, entry.Status)
+		}
+	}
+
+	log.Infof("\naudited %d module cache entries in %s:", len(entries), options.GoPath)
+	log.Infof("    %d verified", counts[mod.AuditVerified])
+	log.Infof("    %d unreferenced", counts[mod.AuditUnreferenced])
+	log.Infof("    %d mismatching", counts[mod.AuditMismatch])
+
+	suspicious := counts[mod.AuditUnreferenced] + counts[mod.AuditMismatch]
+	if suspicious == 0 {
+		return nil
+	}
+
+	if options.quarantine == "" {
+		return errors.Errorf("found %d suspicious module cache entries", suspicious)
+	}
+
+	dir, err := sum.Quarantine(entries, options.quarantine)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("successfully quarantined %d entries to %s", suspicious, dir)
+	return nil
+}
@@ -0,0 +1,133 @@
+package capabilitiescmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	rootcmd "github.com/illikainen/gofer/src/cmd/root"
+	"github.com/illikainen/gofer/src/mod"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var options struct {
+	*rootcmd.Options
+	json      bool
+	verbose   bool
+	workspace string
+	auto      bool
+	ws        *mod.Workspace
+}
+
+var command = &cobra.Command{
+	Use:   "capabilities [flags] [<go.sum>...]",
+	Short: "Report the use of sensitive APIs in the modules of the specified go.sum file(s)",
+	Long: "Report the use of sensitive APIs in the modules of the specified go.sum file(s).\n\n" +
+		"The packages of every module that is extracted to GOPATH are verified against go.sum and " +
+		"inspected for imports of unsafe, os/exec, network, syscall, reflect and cgo packages, calls " +
+		"that write to the filesystem, " +
+		"//go:linkname directives, assembly files and init() functions that call other functions.  " +
+		"Files for every platform are included and the network isn't accessed.",
+	PreRunE: preRun,
+	RunE:    run,
+}
+
+func Command(opts *rootcmd.Options) *cobra.Command {
+	options.Options = opts
+	return command
+}
+
+func init() {
+	flags := command.Flags()
+
+	flags.BoolVarP(&options.json, "json", "", false, "Print the result as JSON")
+	flags.BoolVarP(&options.verbose, "verbose", "", false, "Print the location of every use")
+	flags.StringVarP(&options.workspace, "workspace", "w", "",
+		"Include the go.sum files of the go.work workspace in this directory")
+	flags.BoolVarP(&options.auto, "auto", "", false,
+		"Discover the go.sum files of the current module, repository and workspace")
+}
+
+func preRun(_ *cobra.Command, args []string) error {
+	ws, err := mod.ResolveSumFiles(&mod.ResolveOptions{
+		SumFiles:  args,
+		Workspace: options.workspace,
+		Auto:      options.auto,
+		Log:       log.StandardLogger(),
+	})
+	if err != nil {
+		return err
+	}
+	options.ws = ws
+
+	err = options.Sandbox.AddReadOnlyPath(ws.Paths()...)
+	if err != nil {
+		return err
+	}
+
+	return options.Sandbox.Confine()
+}
+
+func run(cmd *cobra.Command, _ []string) error {
+	cmd.SilenceUsage = true
+
+	sum, err := mod.ReadGoSum(&mod.SumOptions{
+		SumFiles: options.ws.SumFiles,
+		SigPath:  filepath.Join(options.Config.CacheDir, "mod"),
+		GoPath:   options.GoPath,
+		Origins:  options.Config.Origins,
+		Local:    options.ws.Local,
+		Log:      log.StandardLogger(),
+	})
+	if err != nil {
+		return err
+	}
+
+	caps, err := sum.Capabilities()
+	if err != nil {
+		return err
+	}
+
+	if options.json {
+		data, err := json.MarshalIndent(caps, "", "    ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "%s\t\n", strings.Join(append([]string{"module"}, mod.Capabilities...), "\t"))
+	for _, c := range caps {
+		count := c.Count()
+		row := []string{fmt.Sprintf("%s@%s", c.Name, c.Version)}
+		for _, capability := range mod.Capabilities {
+			row = append(row, fmt.Sprintf("%d", count[capability]))
+		}
+		fmt.Fprintf(w, "%s\t\n", strings.Join(row, "\t"))
+	}
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+
+	for _, c := range caps {
+		for _, msg := range c.Errors {
+			log.Warnf("%s@%s: %s", c.Name, c.Version, msg)
+		}
+
+		if options.verbose {
+			for _, use := range c.Uses {
+				log.Infof("%s@%s: %s: %s: %s", c.Name, c.Version, use.Capability, use.Position, use.Detail)
+			}
+		}
+	}
+
+	return nil
+}
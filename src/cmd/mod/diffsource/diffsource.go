@@ -0,0 +1,147 @@
+package diffsourcecmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	rootcmd "github.com/illikainen/gofer/src/cmd/root"
+	"github.com/illikainen/gofer/src/mod"
+
+	"github.com/illikainen/go-cryptor/src/blob"
+	"github.com/illikainen/go-utils/src/seq"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var options struct {
+	*rootcmd.Options
+	include   []string
+	exclude   []string
+	maxSize   int64
+	context   int
+	workspace string
+	auto      bool
+	ws        *mod.Workspace
+}
+
+var command = &cobra.Command{
+	Use:   "diff-source [flags] <module> <old> <new> [<go.sum>...]",
+	Short: "Show the source changes between two versions of a module",
+	Long: "Show the source changes between two versions of a module.\n\n" +
+		"Both versions must be listed in the specified go.sum file(s).  They're read from GOPATH " +
+		"zips or signed blobs, and only content with an h1 that matches go.sum is used.  Binary " +
+		"and large files are listed separately instead of being diffed.",
+	Args:    cobra.MinimumNArgs(3),
+	PreRunE: preRun,
+	RunE:    run,
+}
+
+func Command(opts *rootcmd.Options) *cobra.Command {
+	options.Options = opts
+	return command
+}
+
+func init() {
+	flags := command.Flags()
+
+	flags.StringSliceVarP(&options.include, "include", "", nil, "Only diff paths that match these globs")
+	flags.StringSliceVarP(&options.exclude, "exclude", "", nil, "Don't diff paths that match these globs")
+	flags.Int64VarP(&options.maxSize, "max-size", "", 1024*1024, "Don't diff files larger than this")
+	flags.IntVarP(&options.context, "unified", "U", 3, "Lines of context")
+	flags.StringVarP(&options.workspace, "workspace", "w", "",
+		"Include the go.sum files of the go.work workspace in this directory")
+	flags.BoolVarP(&options.auto, "auto", "", false,
+		"Discover the go.sum files of the current module, repository and workspace")
+}
+
+func preRun(_ *cobra.Command, args []string) error {
+	ws, err := mod.ResolveSumFiles(&mod.ResolveOptions{
+		SumFiles:  args[3:],
+		Workspace: options.workspace,
+		Auto:      options.auto,
+		Log:       log.StandardLogger(),
+	})
+	if err != nil {
+		return err
+	}
+	options.ws = ws
+
+	err = options.Sandbox.AddReadOnlyPath(ws.Paths()...)
+	if err != nil {
+		return err
+	}
+
+	return options.Sandbox.Confine()
+}
+
+func run(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+
+	keys, err := blob.ReadKeyring(options.PrivKey, options.PubKeys)
+	if err != nil {
+		return err
+	}
+
+	sum, err := mod.ReadGoSum(&mod.SumOptions{
+		SumFiles: options.ws.SumFiles,
+		SigPath:  filepath.Join(options.Config.CacheDir, "mod"),
+		GoPath:   options.GoPath,
+		Origins:  options.Config.Origins,
+		Local:    options.ws.Local,
+		Log:      log.StandardLogger(),
+	})
+	if err != nil {
+		return err
+	}
+
+	srcs := []*mod.Source{}
+	for _, version := range args[1:3] {
+		src, ok := seq.FindBy(sum.Sources, func(src *mod.Source) bool {
+			return src.Name == args[0] && src.Version == version
+		})
+		if !ok {
+			return errors.Errorf("%s@%s: not in the go.sum file(s)", args[0], version)
+		}
+		srcs = append(srcs, src)
+	}
+
+	oldZip, err := srcs[0].ReadVerified(keys)
+	if err != nil {
+		return err
+	}
+
+	newZip, err := srcs[1].ReadVerified(keys)
+	if err != nil {
+		return err
+	}
+
+	changes, err := mod.DiffSource(oldZip, newZip, &mod.DiffSourceOptions{
+		Include: options.include,
+		Exclude: options.exclude,
+		MaxSize: options.maxSize,
+		Context: options.context,
+	})
+	if err != nil {
+		return err
+	}
+
+	other := []*mod.FileChange{}
+	for _, change := range changes {
+		if change.Binary || change.Large {
+			other = append(other, change)
+			continue
+		}
+		fmt.Print(change.Diff)
+	}
+
+	if len(other) > 0 {
+		fmt.Println("\nbinary and large files:")
+		for _, change := range other {
+			fmt.Printf("    %s\n", change)
+		}
+	}
+
+	log.Infof("%d file(s) changed between %s and %s", len(changes), srcs[0], srcs[1])
+	return nil
+}
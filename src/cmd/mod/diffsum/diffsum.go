@@ -0,0 +1,101 @@
+package diffsumcmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	rootcmd "github.com/illikainen/gofer/src/cmd/root"
+	"github.com/illikainen/gofer/src/mod"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var options struct {
+	*rootcmd.Options
+	repo string
+	file string
+}
+
+var command = &cobra.Command{
+	Use:   "diff-sum [flags] <old> <new>",
+	Short: "Compare the modules in two versions of a go.sum file",
+	Long: "Compare the modules in two versions of a go.sum file.\n\n" +
+		"Each side is either a go.sum file or a git revision in the form <rev> or <rev>:<path>.  " +
+		"Added, removed, upgraded and downgraded modules are reported together with checksum " +
+		"changes for unchanged versions and new versions that haven't been signed.",
+	Args:    cobra.ExactArgs(2),
+	PreRunE: preRun,
+	RunE:    run,
+}
+
+func Command(opts *rootcmd.Options) *cobra.Command {
+	options.Options = opts
+	return command
+}
+
+func init() {
+	flags := command.Flags()
+
+	flags.StringVarP(&options.repo, "repo", "r", ".", "Git repository for revisions")
+	flags.StringVarP(&options.file, "file", "f", "go.sum", "Path to go.sum in the repository for revisions")
+}
+
+func preRun(_ *cobra.Command, args []string) error {
+	for _, arg := range args {
+		paths, err := mod.SumRefPaths(arg, []string{filepath.Join(options.repo, options.file)})
+		if err != nil {
+			return err
+		}
+
+		err = options.Sandbox.AddReadOnlyPath(paths...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return options.Sandbox.Confine()
+}
+
+func run(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+
+	sums := []*mod.SumFile{}
+	for _, arg := range args {
+		sum, err := mod.ReadSumRef(arg, []string{filepath.Join(options.repo, options.file)}, &mod.SumOptions{
+			SigPath: filepath.Join(options.Config.CacheDir, "mod"),
+			GoPath:  options.GoPath,
+			Origins: options.Config.Origins,
+			Log:     log.StandardLogger(),
+		})
+		if err != nil {
+			return err
+		}
+		sums = append(sums, sum)
+	}
+
+	changes, err := sums[1].Diff(sums[0])
+	if err != nil {
+		return err
+	}
+
+	suspicious := 0
+	for _, change := range changes {
+		fmt.Println(change)
+		if len(change.Unsigned) > 0 {
+			fmt.Printf("    not signed: %s\n", strings.Join(change.Unsigned, ", "))
+		}
+
+		if change.Suspicious() {
+			log.Warnf("%s@%s: checksum changed without a version change", change.Name, change.Old)
+			suspicious++
+		}
+	}
+
+	if suspicious > 0 {
+		return errors.Errorf("%d checksum(s) changed without a version change", suspicious)
+	}
+	return nil
+}
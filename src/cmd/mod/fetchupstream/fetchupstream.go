@@ -0,0 +1,160 @@
+package fetchupstreamcmd
+
+import (
+	"path/filepath"
+
+	rootcmd "github.com/illikainen/gofer/src/cmd/root"
+	"github.com/illikainen/gofer/src/mod"
+
+	"github.com/illikainen/go-cryptor/src/blob"
+	"github.com/illikainen/go-utils/src/seq"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var options struct {
+	*rootcmd.Options
+	proxy     string
+	sumdb     string
+	extract   bool
+	workspace string
+	auto      bool
+	ws        *mod.Workspace
+}
+
+var command = &cobra.Command{
+	Use:   "fetch-upstream [flags] [<go.sum>...]",
+	Short: "Download modules and metadata referenced in the specified go.sum file(s) from a module proxy",
+	Long: "Download modules and metadata referenced in the specified go.sum file(s) from a module proxy.\n\n" +
+		"The .mod, .info and .zip files are retrieved with the GOPROXY protocol and only written to " +
+		"GOPATH once the .mod and .zip files match go.sum and, unless --sumdb is off, the checksum " +
+		"database.  The checksum database is accessed through the proxy, and connections to every " +
+		"other host are refused by the HTTP client.  Note that the sandbox can't restrict the " +
+		"network to the proxy host; it shares the whole network, so code that is compromised inside " +
+		"the sandbox can reach other hosts.  The verified files can then be signed with sign-cache.",
+	PreRunE: preRun,
+	RunE:    run,
+}
+
+func Command(opts *rootcmd.Options) *cobra.Command {
+	options.Options = opts
+	return command
+}
+
+func init() {
+	flags := command.Flags()
+
+	flags.StringVarP(&options.proxy, "proxy", "", "", "Module proxy URL (default from the configuration)")
+	flags.StringVarP(&options.sumdb, "sumdb", "", "",
+		"Checksum database key or name, or off (default from the configuration)")
+	flags.BoolVarP(&options.extract, "extract", "", false, "Extract the verified module code in GOPATH")
+	flags.StringVarP(&options.workspace, "workspace", "w", "",
+		"Include the go.sum files of the go.work workspace in this directory")
+	flags.BoolVarP(&options.auto, "auto", "", false,
+		"Discover the go.sum files of the current module, repository and workspace")
+}
+
+func preRun(_ *cobra.Command, args []string) error {
+	options.proxy, _ = seq.Coalesce(options.proxy, options.Config.Proxy)
+	if options.proxy == "" {
+		return errors.Errorf("required flag(s) \"proxy\" not set")
+	}
+
+	options.sumdb, _ = seq.Coalesce(options.sumdb, options.Config.SumDB)
+	if options.sumdb == "off" {
+		options.sumdb = ""
+	}
+
+	ws, err := mod.ResolveSumFiles(&mod.ResolveOptions{
+		SumFiles:  args,
+		Workspace: options.workspace,
+		Auto:      options.auto,
+		Log:       log.StandardLogger(),
+	})
+	if err != nil {
+		return err
+	}
+	options.ws = ws
+
+	err = options.Sandbox.AddReadOnlyPath(ws.Paths()...)
+	if err != nil {
+		return err
+	}
+
+	// The sandbox can only share or unshare the network as a whole, so
+	// the proxy host is only enforced in-process by mod.Proxy.  That
+	// doesn't hold if the process itself is compromised.
+	options.Sandbox.SetShareNet(true)
+	return options.Sandbox.Confine()
+}
+
+func run(cmd *cobra.Command, _ []string) error {
+	cmd.SilenceUsage = true
+
+	keys, err := blob.ReadKeyring(options.PrivKey, options.PubKeys)
+	if err != nil {
+		return err
+	}
+
+	proxy, err := mod.NewProxy(options.proxy)
+	if err != nil {
+		return err
+	}
+
+	var db *mod.SumDB
+	if options.sumdb != "" {
+		name, err := mod.SumDBName(options.sumdb)
+		if err != nil {
+			return err
+		}
+
+		db, err = mod.NewSumDB(&mod.SumDBOptions{
+			Key:       options.sumdb,
+			URL:       proxy.SumDBURL(name),
+			Client:    proxy.Client,
+			CacheDir:  filepath.Join(options.GoPath, "pkg", "mod", "cache", "download", "sumdb"),
+			ConfigDir: filepath.Join(options.GoPath, "pkg", "sumdb"),
+			Log:       log.StandardLogger(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	sum, err := mod.ReadGoSum(&mod.SumOptions{
+		SumFiles: options.ws.SumFiles,
+		SigPath:  filepath.Join(options.Config.CacheDir, "mod"),
+		GoPath:   options.GoPath,
+		Origins:  options.Config.Origins,
+		Local:    options.ws.Local,
+		Log:      log.StandardLogger(),
+	})
+	if err != nil {
+		return err
+	}
+
+	result, err := sum.FetchUpstream(proxy, db)
+	if err != nil {
+		return err
+	}
+
+	_, err = sum.WriteIndex(keys)
+	if err != nil {
+		return err
+	}
+
+	if options.extract {
+		err = sum.Extract()
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Infof("successfully retrieved module(s) and metadata from %s:", options.proxy)
+	log.Infof("    %d mod files", len(result.ModFiles))
+	log.Infof("    %d info files", len(result.InfoFiles))
+	log.Infof("    %d zip sources", len(result.Sources))
+	log.Infof("    %d files already in %s", len(result.Existing), options.GoPath)
+	return nil
+}
@@ -16,16 +16,19 @@ import (
 )
 
 var command = &cobra.Command{
-	Use:     "get [flags] <go.sum>...",
+	Use:     "get [flags] [<go.sum>...]",
 	Short:   "Download modules and metadata referenced in the specified go.sum file(s)",
 	PreRunE: preRun,
 	RunE:    run,
-	Args:    cobra.MinimumNArgs(1),
 }
 
 var options struct {
 	*rootcmd.Options
-	url string
+	url       string
+	extract   bool
+	workspace string
+	auto      bool
+	ws        *mod.Workspace
 }
 
 func Command(opts *rootcmd.Options) *cobra.Command {
@@ -37,6 +40,11 @@ func init() {
 	flags := command.Flags()
 
 	flags.StringVarP(&options.url, "url", "", "", "repository url")
+	flags.BoolVarP(&options.extract, "extract", "", false, "Extract the verified module code in GOPATH")
+	flags.StringVarP(&options.workspace, "workspace", "w", "",
+		"Include the go.sum files of the go.work workspace in this directory")
+	flags.BoolVarP(&options.auto, "auto", "", false,
+		"Discover the go.sum files of the current module, repository and workspace")
 }
 
 func preRun(_ *cobra.Command, args []string) error {
@@ -57,7 +65,18 @@ func preRun(_ *cobra.Command, args []string) error {
 		}
 	}
 
-	err = options.Sandbox.AddReadOnlyPath(args...)
+	ws, err := mod.ResolveSumFiles(&mod.ResolveOptions{
+		SumFiles:  args,
+		Workspace: options.workspace,
+		Auto:      options.auto,
+		Log:       log.StandardLogger(),
+	})
+	if err != nil {
+		return err
+	}
+	options.ws = ws
+
+	err = options.Sandbox.AddReadOnlyPath(ws.Paths()...)
 	if err != nil {
 		return err
 	}
@@ -65,7 +84,7 @@ func preRun(_ *cobra.Command, args []string) error {
 	return options.Sandbox.Confine()
 }
 
-func run(cmd *cobra.Command, args []string) (err error) {
+func run(cmd *cobra.Command, _ []string) (err error) {
 	cmd.SilenceUsage = true
 
 	keys, err := blob.ReadKeyring(options.PrivKey, options.PubKeys)
@@ -74,20 +93,50 @@ func run(cmd *cobra.Command, args []string) (err error) {
 	}
 
 	sum, err := mod.ReadGoSum(&mod.SumOptions{
-		SumFiles: args,
+		SumFiles: options.ws.SumFiles,
 		SigPath:  filepath.Join(options.Config.CacheDir, "mod"),
 		GoPath:   options.GoPath,
+		Origins:  options.Config.Origins,
+		Local:    options.ws.Local,
 		Log:      log.StandardLogger(),
 	})
 	if err != nil {
 		return err
 	}
 
-	err = sum.DownloadAndVerify(options.url, keys)
+	cooldown, err := mod.NewCooldown(&options.Config.Cooldown)
+	if err != nil {
+		return err
+	}
+
+	sigPath := filepath.Join(options.Config.CacheDir, "mod")
+	typosquat, err := mod.NewTyposquat(&options.Config.Typosquat, sigPath, keys)
+	if err != nil {
+		return err
+	}
+
+	_, err = typosquat.Check(sum)
 	if err != nil {
 		return err
 	}
 
-	log.Infof("successfully retrieved module(s) and metadata in %s", strings.Join(args, ", "))
+	err = sum.DownloadAndVerify(options.url, keys, cooldown)
+	if err != nil {
+		return err
+	}
+
+	_, err = sum.WriteIndex(keys)
+	if err != nil {
+		return err
+	}
+
+	if options.extract {
+		err = sum.Extract()
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Infof("successfully retrieved module(s) and metadata in %s", strings.Join(options.ws.SumFiles, ", "))
 	return nil
 }
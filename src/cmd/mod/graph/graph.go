@@ -0,0 +1,127 @@
+package graphcmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	rootcmd "github.com/illikainen/gofer/src/cmd/root"
+	"github.com/illikainen/gofer/src/mod"
+
+	"github.com/illikainen/go-cryptor/src/blob"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var options struct {
+	*rootcmd.Options
+	modFiles  []string
+	workspace string
+	auto      bool
+	ws        *mod.Workspace
+}
+
+var command = &cobra.Command{
+	Use:   "graph [flags] [<go.sum>...]",
+	Short: "Print the module requirement graph computed from verified .mod files",
+	Long: "Print the module requirement graph computed from verified .mod files.\n\n" +
+		"The graph and build list are computed with minimal version selection over .mod files that " +
+		"are verified against the specified go.sum file(s), without invoking the Go command or " +
+		"accessing the network.  Every go.sum entry is classified based on whether the build needs it.",
+	PreRunE: preRun,
+	RunE:    run,
+}
+
+func Command(opts *rootcmd.Options) *cobra.Command {
+	options.Options = opts
+	return command
+}
+
+func init() {
+	flags := command.Flags()
+
+	flags.StringSliceVarP(&options.modFiles, "modfile", "m", []string{"go.mod"}, "go.mod for the main module(s)")
+	flags.StringVarP(&options.workspace, "workspace", "w", "",
+		"Include the go.sum files of the go.work workspace in this directory")
+	flags.BoolVarP(&options.auto, "auto", "", false,
+		"Discover the go.sum files of the current module, repository and workspace")
+}
+
+func preRun(_ *cobra.Command, args []string) error {
+	ws, err := mod.ResolveSumFiles(&mod.ResolveOptions{
+		SumFiles:  args,
+		Workspace: options.workspace,
+		Auto:      options.auto,
+		Log:       log.StandardLogger(),
+	})
+	if err != nil {
+		return err
+	}
+	options.ws = ws
+
+	err = options.Sandbox.AddReadOnlyPath(ws.Paths()...)
+	if err != nil {
+		return err
+	}
+
+	err = options.Sandbox.AddReadOnlyPath(options.modFiles...)
+	if err != nil {
+		return err
+	}
+
+	return options.Sandbox.Confine()
+}
+
+func run(cmd *cobra.Command, _ []string) error {
+	cmd.SilenceUsage = true
+
+	keys, err := blob.ReadKeyring(options.PrivKey, options.PubKeys)
+	if err != nil {
+		return err
+	}
+
+	sum, err := mod.ReadGoSum(&mod.SumOptions{
+		SumFiles: options.ws.SumFiles,
+		SigPath:  filepath.Join(options.Config.CacheDir, "mod"),
+		GoPath:   options.GoPath,
+		Origins:  options.Config.Origins,
+		Local:    options.ws.Local,
+		Log:      log.StandardLogger(),
+	})
+	if err != nil {
+		return err
+	}
+
+	graph, err := sum.Graph(&mod.GraphOptions{
+		ModFiles: options.modFiles,
+		Keyring:  keys,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, line := range graph.Lines() {
+		fmt.Printf("%s %s\n", line[0], line[1])
+	}
+
+	for _, missing := range graph.Missing {
+		log.Warnf("%s: no verified .mod file, its requirements are unknown", missing)
+	}
+
+	unneeded := 0
+	for _, entry := range graph.Classify(sum) {
+		name := entry.Mod.String()
+		if entry.GoMod {
+			name += "/go.mod"
+		}
+
+		if entry.Status == mod.SumUnneeded {
+			log.Warnf("%s: %s", name, entry.Status)
+			unneeded++
+		} else {
+			log.Infof("%s: %s", name, entry.Status)
+		}
+	}
+
+	log.Infof("build list: %d module(s), %d unneeded go.sum entries", len(graph.BuildList), unneeded)
+	return nil
+}
@@ -0,0 +1,93 @@
+package indexcmd
+
+import (
+	"path/filepath"
+
+	rootcmd "github.com/illikainen/gofer/src/cmd/root"
+	"github.com/illikainen/gofer/src/mod"
+
+	"github.com/illikainen/go-cryptor/src/blob"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var options struct {
+	*rootcmd.Options
+	workspace string
+	auto      bool
+	ws        *mod.Workspace
+}
+
+var command = &cobra.Command{
+	Use:   "index [flags] [<go.sum>...]",
+	Short: "Write GOPROXY list files for verified GOPATH modules referenced in the specified go.sum file(s)",
+	Long: "Write GOPROXY list files for verified GOPATH modules referenced in the specified " +
+		"go.sum file(s).\n\nThe generated @v/list and @latest files allow the Go command to resolve versions " +
+		"offline with GOPROXY=file://$GOPATH/pkg/mod/cache/download.",
+	PreRunE: preRun,
+	RunE:    run,
+}
+
+func Command(opts *rootcmd.Options) *cobra.Command {
+	options.Options = opts
+	return command
+}
+
+func init() {
+	flags := command.Flags()
+
+	flags.StringVarP(&options.workspace, "workspace", "w", "",
+		"Include the go.sum files of the go.work workspace in this directory")
+	flags.BoolVarP(&options.auto, "auto", "", false,
+		"Discover the go.sum files of the current module, repository and workspace")
+}
+
+func preRun(_ *cobra.Command, args []string) error {
+	ws, err := mod.ResolveSumFiles(&mod.ResolveOptions{
+		SumFiles:  args,
+		Workspace: options.workspace,
+		Auto:      options.auto,
+		Log:       log.StandardLogger(),
+	})
+	if err != nil {
+		return err
+	}
+	options.ws = ws
+
+	err = options.Sandbox.AddReadOnlyPath(ws.Paths()...)
+	if err != nil {
+		return err
+	}
+
+	return options.Sandbox.Confine()
+}
+
+func run(cmd *cobra.Command, _ []string) error {
+	cmd.SilenceUsage = true
+
+	keys, err := blob.ReadKeyring(options.PrivKey, options.PubKeys)
+	if err != nil {
+		return err
+	}
+
+	sum, err := mod.ReadGoSum(&mod.SumOptions{
+		SumFiles: options.ws.SumFiles,
+		SigPath:  filepath.Join(options.Config.CacheDir, "mod"),
+		GoPath:   options.GoPath,
+		Origins:  options.Config.Origins,
+		Local:    options.ws.Local,
+		Log:      log.StandardLogger(),
+	})
+	if err != nil {
+		return err
+	}
+
+	ir, err := sum.WriteIndex(keys)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("successfully wrote %d list file(s) and %d latest file(s) to %s",
+		len(ir.Lists), len(ir.Latests), options.GoPath)
+	return nil
+}
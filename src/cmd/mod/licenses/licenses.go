@@ -0,0 +1,140 @@
+package licensescmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	rootcmd "github.com/illikainen/gofer/src/cmd/root"
+	"github.com/illikainen/gofer/src/mod"
+
+	"github.com/illikainen/go-cryptor/src/blob"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var options struct {
+	*rootcmd.Options
+	format    string
+	workspace string
+	auto      bool
+	ws        *mod.Workspace
+}
+
+var command = &cobra.Command{
+	Use:   "licenses [flags] [<go.sum>...]",
+	Short: "Report the licenses of the modules in the specified go.sum file(s)",
+	Long: "Report the licenses of the modules in the specified go.sum file(s).\n\n" +
+		"LICENSE, LICENCE, COPYING and UNLICENSE files in the verified module directories or zips " +
+		"are classified by their SPDX identifier with a bundled license corpus.  The command fails " +
+		"if a module has a license that isn't allowed by the license policy in the configuration.",
+	PreRunE: preRun,
+	RunE:    run,
+}
+
+func Command(opts *rootcmd.Options) *cobra.Command {
+	options.Options = opts
+	return command
+}
+
+func init() {
+	flags := command.Flags()
+
+	flags.StringVarP(&options.format, "format", "f", "csv", "Output format (csv, json)")
+	flags.StringVarP(&options.workspace, "workspace", "w", "",
+		"Include the go.sum files of the go.work workspace in this directory")
+	flags.BoolVarP(&options.auto, "auto", "", false,
+		"Discover the go.sum files of the current module, repository and workspace")
+}
+
+func preRun(_ *cobra.Command, args []string) error {
+	if options.format != "csv" && options.format != "json" {
+		return errors.Errorf("invalid format: %s", options.format)
+	}
+
+	ws, err := mod.ResolveSumFiles(&mod.ResolveOptions{
+		SumFiles:  args,
+		Workspace: options.workspace,
+		Auto:      options.auto,
+		Log:       log.StandardLogger(),
+	})
+	if err != nil {
+		return err
+	}
+	options.ws = ws
+
+	err = options.Sandbox.AddReadOnlyPath(ws.Paths()...)
+	if err != nil {
+		return err
+	}
+
+	return options.Sandbox.Confine()
+}
+
+func run(cmd *cobra.Command, _ []string) error {
+	cmd.SilenceUsage = true
+
+	keys, err := blob.ReadKeyring(options.PrivKey, options.PubKeys)
+	if err != nil {
+		return err
+	}
+
+	sum, err := mod.ReadGoSum(&mod.SumOptions{
+		SumFiles: options.ws.SumFiles,
+		SigPath:  filepath.Join(options.Config.CacheDir, "mod"),
+		GoPath:   options.GoPath,
+		Origins:  options.Config.Origins,
+		Local:    options.ws.Local,
+		Log:      log.StandardLogger(),
+	})
+	if err != nil {
+		return err
+	}
+
+	licenses, err := sum.Licenses(keys)
+	if err != nil {
+		return err
+	}
+
+	switch options.format {
+	case "json":
+		data, err := json.MarshalIndent(licenses, "", "    ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		err := w.Write([]string{"module", "version", "licenses", "path", "license"})
+		if err != nil {
+			return err
+		}
+
+		for _, ml := range licenses {
+			rows := [][]string{}
+			for _, f := range ml.Files {
+				rows = append(rows, []string{
+					ml.Name, ml.Version, strings.Join(ml.Licenses, " "), f.Path, strings.Join(f.Licenses, " "),
+				})
+			}
+			if len(rows) == 0 {
+				rows = append(rows, []string{ml.Name, ml.Version, strings.Join(ml.Licenses, " "), "", ""})
+			}
+
+			err := w.WriteAll(rows)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	policy := &options.Config.Licenses
+	if len(policy.Allow) > 0 || len(policy.Deny) > 0 {
+		return sum.CheckLicenses(licenses, policy)
+	}
+	return nil
+}
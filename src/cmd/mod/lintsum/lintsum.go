@@ -0,0 +1,119 @@
+package lintsumcmd
+
+import (
+	"path/filepath"
+
+	rootcmd "github.com/illikainen/gofer/src/cmd/root"
+	"github.com/illikainen/gofer/src/mod"
+
+	"github.com/illikainen/go-cryptor/src/blob"
+	"github.com/illikainen/go-utils/src/seq"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var options struct {
+	*rootcmd.Options
+	modFiles  []string
+	workspace string
+	auto      bool
+	ws        *mod.Workspace
+}
+
+var command = &cobra.Command{
+	Use:   "lint-sum [flags] [<go.sum>...]",
+	Short: "Check the consistency of the specified go.sum file(s) with go.mod",
+	Long: "Check the consistency of the specified go.sum file(s) with go.mod.\n\n" +
+		"Conflicting checksums, module lines without a /go.mod line, /go.mod lines that are " +
+		"required by the module graph but missing, and entries that are unreachable from the main " +
+		"module(s) are reported.  The command fails if any issue is found.",
+	PreRunE: preRun,
+	RunE:    run,
+}
+
+func Command(opts *rootcmd.Options) *cobra.Command {
+	options.Options = opts
+	return command
+}
+
+func init() {
+	flags := command.Flags()
+
+	flags.StringSliceVarP(&options.modFiles, "modfile", "m", []string{"go.mod"}, "go.mod for the main module(s)")
+	flags.StringVarP(&options.workspace, "workspace", "w", "",
+		"Include the go.sum files of the go.work workspace in this directory")
+	flags.BoolVarP(&options.auto, "auto", "", false,
+		"Discover the go.sum files of the current module, repository and workspace")
+}
+
+func preRun(_ *cobra.Command, args []string) error {
+	ws, err := mod.ResolveSumFiles(&mod.ResolveOptions{
+		SumFiles:  args,
+		Workspace: options.workspace,
+		Auto:      options.auto,
+		Log:       log.StandardLogger(),
+	})
+	if err != nil {
+		return err
+	}
+	options.ws = ws
+
+	err = options.Sandbox.AddReadOnlyPath(ws.Paths()...)
+	if err != nil {
+		return err
+	}
+
+	err = options.Sandbox.AddReadOnlyPath(options.modFiles...)
+	if err != nil {
+		return err
+	}
+
+	return options.Sandbox.Confine()
+}
+
+func run(cmd *cobra.Command, _ []string) error {
+	cmd.SilenceUsage = true
+
+	keys, err := blob.ReadKeyring(options.PrivKey, options.PubKeys)
+	if err != nil {
+		return err
+	}
+
+	sum, err := mod.ReadGoSum(&mod.SumOptions{
+		SumFiles: options.ws.SumFiles,
+		SigPath:  filepath.Join(options.Config.CacheDir, "mod"),
+		GoPath:   options.GoPath,
+		Origins:  options.Config.Origins,
+		Local:    options.ws.Local,
+		Log:      log.StandardLogger(),
+	})
+	if err != nil {
+		return err
+	}
+
+	issues, graph, err := sum.Lint(&mod.GraphOptions{
+		ModFiles: options.modFiles,
+		Keyring:  keys,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, missing := range graph.Missing {
+		if !seq.Contains(graph.Unlisted, missing) {
+			log.Warnf("%s: no verified .mod file, its requirements are not checked", missing)
+		}
+	}
+
+	for _, issue := range issues {
+		log.Error(issue)
+	}
+
+	if len(issues) > 0 {
+		return errors.Errorf("found %d issue(s) in the go.sum file(s)", len(issues))
+	}
+
+	log.Infof("no issues found in %d go.sum file(s)", len(options.ws.SumFiles))
+	return nil
+}
@@ -1,11 +1,27 @@
 package modcmd
 
 import (
+	auditcachecmd "github.com/illikainen/gofer/src/cmd/mod/auditcache"
 	cachedircmd "github.com/illikainen/gofer/src/cmd/mod/cachedir"
+	capabilitiescmd "github.com/illikainen/gofer/src/cmd/mod/capabilities"
+	diffsourcecmd "github.com/illikainen/gofer/src/cmd/mod/diffsource"
+	diffsumcmd "github.com/illikainen/gofer/src/cmd/mod/diffsum"
+	fetchupstreamcmd "github.com/illikainen/gofer/src/cmd/mod/fetchupstream"
 	getcmd "github.com/illikainen/gofer/src/cmd/mod/get"
+	graphcmd "github.com/illikainen/gofer/src/cmd/mod/graph"
 	h1cmd "github.com/illikainen/gofer/src/cmd/mod/h1"
+	indexcmd "github.com/illikainen/gofer/src/cmd/mod/index"
+	licensescmd "github.com/illikainen/gofer/src/cmd/mod/licenses"
+	lintsumcmd "github.com/illikainen/gofer/src/cmd/mod/lintsum"
+	outdatedcmd "github.com/illikainen/gofer/src/cmd/mod/outdated"
+	reproducecmd "github.com/illikainen/gofer/src/cmd/mod/reproduce"
+	reviewcmd "github.com/illikainen/gofer/src/cmd/mod/review"
+	scancmd "github.com/illikainen/gofer/src/cmd/mod/scan"
 	signcachecmd "github.com/illikainen/gofer/src/cmd/mod/signcache"
+	sumdbcheckcmd "github.com/illikainen/gofer/src/cmd/mod/sumdbcheck"
 	verifycmd "github.com/illikainen/gofer/src/cmd/mod/verify"
+	vulncmd "github.com/illikainen/gofer/src/cmd/mod/vuln"
+	whycmd "github.com/illikainen/gofer/src/cmd/mod/why"
 	rootcmd "github.com/illikainen/gofer/src/cmd/root"
 
 	"github.com/spf13/cobra"
@@ -17,10 +33,26 @@ var command = &cobra.Command{
 }
 
 func Command(opts *rootcmd.Options) *cobra.Command {
+	command.AddCommand(auditcachecmd.Command(opts))
 	command.AddCommand(cachedircmd.Command(opts))
+	command.AddCommand(capabilitiescmd.Command(opts))
+	command.AddCommand(diffsourcecmd.Command(opts))
+	command.AddCommand(diffsumcmd.Command(opts))
+	command.AddCommand(fetchupstreamcmd.Command(opts))
 	command.AddCommand(getcmd.Command(opts))
+	command.AddCommand(graphcmd.Command(opts))
 	command.AddCommand(h1cmd.Command(opts))
+	command.AddCommand(indexcmd.Command(opts))
+	command.AddCommand(licensescmd.Command(opts))
+	command.AddCommand(lintsumcmd.Command(opts))
+	command.AddCommand(outdatedcmd.Command(opts))
+	command.AddCommand(reproducecmd.Command(opts))
+	command.AddCommand(reviewcmd.Command(opts))
+	command.AddCommand(scancmd.Command(opts))
 	command.AddCommand(signcachecmd.Command(opts))
+	command.AddCommand(sumdbcheckcmd.Command(opts))
 	command.AddCommand(verifycmd.Command(opts))
+	command.AddCommand(vulncmd.Command(opts))
+	command.AddCommand(whycmd.Command(opts))
 	return command
 }
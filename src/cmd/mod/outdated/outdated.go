@@ -0,0 +1,148 @@
+package outdatedcmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	rootcmd "github.com/illikainen/gofer/src/cmd/root"
+	"github.com/illikainen/gofer/src/mod"
+
+	"github.com/illikainen/go-cryptor/src/blob"
+	"github.com/illikainen/go-utils/src/seq"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var options struct {
+	*rootcmd.Options
+	mirror    string
+	json      bool
+	workspace string
+	auto      bool
+	ws        *mod.Workspace
+}
+
+var command = &cobra.Command{
+	Use:   "outdated [flags] [<go.sum>...]",
+	Short: "List newer versions of the modules in the specified go.sum file(s)",
+	Long: "List newer versions of the modules in the specified go.sum file(s).\n\n" +
+		"Versions are looked up in the signature directory and in the @v/list files of the " +
+		"mirror, if any.  Signed versions have already been approved, and the latest version of " +
+		"every newer major version is listed separately since it's a different module path.  " +
+		"The network is never accessed.",
+	PreRunE: preRun,
+	RunE:    run,
+}
+
+func Command(opts *rootcmd.Options) *cobra.Command {
+	options.Options = opts
+	return command
+}
+
+func init() {
+	flags := command.Flags()
+
+	flags.StringVarP(&options.mirror, "mirror", "", "",
+		"Directory laid out according to the GOPROXY protocol (default from the configuration)")
+	flags.BoolVarP(&options.json, "json", "", false, "Print the result as JSON")
+	flags.StringVarP(&options.workspace, "workspace", "w", "",
+		"Include the go.sum files of the go.work workspace in this directory")
+	flags.BoolVarP(&options.auto, "auto", "", false,
+		"Discover the go.sum files of the current module, repository and workspace")
+}
+
+func preRun(_ *cobra.Command, args []string) error {
+	ws, err := mod.ResolveSumFiles(&mod.ResolveOptions{
+		SumFiles:  args,
+		Workspace: options.workspace,
+		Auto:      options.auto,
+		Log:       log.StandardLogger(),
+	})
+	if err != nil {
+		return err
+	}
+	options.ws = ws
+
+	err = options.Sandbox.AddReadOnlyPath(ws.Paths()...)
+	if err != nil {
+		return err
+	}
+
+	options.mirror, _ = seq.Coalesce(options.mirror, options.Config.Mirror)
+	if options.mirror != "" {
+		err = options.Sandbox.AddReadOnlyPath(options.mirror)
+		if err != nil {
+			return err
+		}
+	}
+
+	return options.Sandbox.Confine()
+}
+
+func run(cmd *cobra.Command, _ []string) error {
+	cmd.SilenceUsage = true
+
+	keys, err := blob.ReadKeyring(options.PrivKey, options.PubKeys)
+	if err != nil {
+		return err
+	}
+
+	sum, err := mod.ReadGoSum(&mod.SumOptions{
+		SumFiles: options.ws.SumFiles,
+		SigPath:  filepath.Join(options.Config.CacheDir, "mod"),
+		GoPath:   options.GoPath,
+		Origins:  options.Config.Origins,
+		Local:    options.ws.Local,
+		Log:      log.StandardLogger(),
+	})
+	if err != nil {
+		return err
+	}
+
+	outdated, err := sum.Outdated(options.mirror, keys)
+	if err != nil {
+		return err
+	}
+
+	if options.json {
+		data, err := json.MarshalIndent(outdated, "", "    ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "module\tcurrent\tavailable\tkind\tpublished\tsource\t\n")
+	for _, o := range outdated {
+		for _, u := range append(append([]*mod.Update{}, o.Updates...), o.Majors...) {
+			available := u.Version
+			if u.Name != o.Name {
+				available = u.String()
+			}
+
+			source := []string{}
+			if u.Signed {
+				source = append(source, "signed")
+			}
+			if u.Mirrored {
+				source = append(source, "mirror")
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t\n", o.Name, o.Version, available, u.Kind,
+				u.Published, strings.Join(source, ","))
+		}
+	}
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+
+	log.Infof("%d module(s) with newer versions", len(outdated))
+	return nil
+}
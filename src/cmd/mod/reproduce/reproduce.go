@@ -0,0 +1,127 @@
+package reproducecmd
+
+import (
+	"path/filepath"
+	"strings"
+
+	rootcmd "github.com/illikainen/gofer/src/cmd/root"
+	"github.com/illikainen/gofer/src/mod"
+
+	"github.com/illikainen/go-utils/src/fn"
+	"github.com/illikainen/go-utils/src/seq"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var options struct {
+	*rootcmd.Options
+	repo      string
+	subdir    string
+	workspace string
+	auto      bool
+	ws        *mod.Workspace
+}
+
+var command = &cobra.Command{
+	Use:   "reproduce [flags] <module>@<version> [<go.sum>...]",
+	Short: "Rebuild a module zip from a local git repository and compare its h1 with go.sum",
+	Long: "Rebuild a module zip from a local git repository and compare its h1 with go.sum.\n\n" +
+		"The tag or pseudo-version commit is archived from a clone of the repository the same way " +
+		"as the Go command does it, which provides independent evidence that the module served by " +
+		"a proxy matches the upstream source.",
+	Args:    cobra.MinimumNArgs(1),
+	PreRunE: preRun,
+	RunE:    run,
+}
+
+func Command(opts *rootcmd.Options) *cobra.Command {
+	options.Options = opts
+	return command
+}
+
+func init() {
+	flags := command.Flags()
+
+	flags.StringVarP(&options.repo, "repo", "r", "", "Local clone or mirror of the module repository")
+	fn.Must(command.MarkFlagRequired("repo"))
+
+	flags.StringVarP(&options.subdir, "subdir", "", "",
+		"Module subdirectory in the repository (default: derived from the module path)")
+	flags.StringVarP(&options.workspace, "workspace", "w", "",
+		"Include the go.sum files of the go.work workspace in this directory")
+	flags.BoolVarP(&options.auto, "auto", "", false,
+		"Discover the go.sum files of the current module, repository and workspace")
+}
+
+func preRun(_ *cobra.Command, args []string) error {
+	ws, err := mod.ResolveSumFiles(&mod.ResolveOptions{
+		SumFiles:  args[1:],
+		Workspace: options.workspace,
+		Auto:      options.auto,
+		Log:       log.StandardLogger(),
+	})
+	if err != nil {
+		return err
+	}
+	options.ws = ws
+
+	err = options.Sandbox.AddReadOnlyPath(ws.Paths()...)
+	if err != nil {
+		return err
+	}
+
+	err = options.Sandbox.AddReadOnlyPath(options.repo)
+	if err != nil {
+		return err
+	}
+
+	return options.Sandbox.Confine()
+}
+
+func run(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+
+	name, version, ok := strings.Cut(args[0], "@")
+	if !ok {
+		return errors.Errorf("%s: missing version", args[0])
+	}
+
+	sum, err := mod.ReadGoSum(&mod.SumOptions{
+		SumFiles: options.ws.SumFiles,
+		SigPath:  filepath.Join(options.Config.CacheDir, "mod"),
+		GoPath:   options.GoPath,
+		Origins:  options.Config.Origins,
+		Local:    options.ws.Local,
+		Log:      log.StandardLogger(),
+	})
+	if err != nil {
+		return err
+	}
+
+	src, ok := seq.FindBy(sum.Sources, func(src *mod.Source) bool {
+		return src.Name == name && src.Version == version
+	})
+	if !ok {
+		return errors.Errorf("%s: not in the go.sum file(s)", args[0])
+	}
+
+	result, err := sum.Reproduce(src, options.repo, options.subdir)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("%s: revision %s (%s)", src, result.Revision, result.Commit)
+	if result.Subdir != "" {
+		log.Infof("%s: subdirectory %s", src, result.Subdir)
+	}
+	log.Infof("%s: rebuilt %s", src, result.Checksum)
+	log.Infof("%s: go.sum  %s", src, src.Checksum)
+
+	if !result.Match {
+		return errors.Errorf("%s: the rebuilt zip doesn't match go.sum", src)
+	}
+
+	log.Infof("%s: successfully reproduced", src)
+	return nil
+}
@@ -0,0 +1,132 @@
+package reviewcmd
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	rootcmd "github.com/illikainen/gofer/src/cmd/root"
+	"github.com/illikainen/gofer/src/mod"
+
+	"github.com/illikainen/go-cryptor/src/blob"
+	"github.com/illikainen/go-utils/src/fn"
+	"github.com/illikainen/go-utils/src/seq"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var options struct {
+	*rootcmd.Options
+	verdict   string
+	notes     string
+	base      string
+	workspace string
+	auto      bool
+	ws        *mod.Workspace
+}
+
+var command = &cobra.Command{
+	Use:   "review [flags] <module>@<version> [<go.sum>...]",
+	Short: "Record a signed review of a module version",
+	Long: "Record a signed review of a module version.\n\n" +
+		"The review is bound to the h1 of the module zip in the specified go.sum file(s) and it's " +
+		"stored next to the signed zip.  The reviewer is identified by the fingerprint of the key " +
+		"that signs the review.  Reviews can be required by mod verify with the Review policy in " +
+		"the configuration file.",
+	Args:    cobra.MinimumNArgs(1),
+	PreRunE: preRun,
+	RunE:    run,
+}
+
+func Command(opts *rootcmd.Options) *cobra.Command {
+	options.Options = opts
+	return command
+}
+
+func init() {
+	flags := command.Flags()
+
+	flags.StringVarP(&options.verdict, "verdict", "", "",
+		"Verdict ("+mod.ReviewApproved+" or "+mod.ReviewRejected+")")
+	flags.StringVarP(&options.notes, "notes", "", "", "Review notes")
+	flags.StringVarP(&options.base, "base", "", "", "Version that the changes were reviewed against")
+	flags.StringVarP(&options.workspace, "workspace", "w", "",
+		"Include the go.sum files of the go.work workspace in this directory")
+	flags.BoolVarP(&options.auto, "auto", "", false,
+		"Discover the go.sum files of the current module, repository and workspace")
+
+	fn.Must(command.MarkFlagRequired("verdict"))
+}
+
+func preRun(_ *cobra.Command, args []string) error {
+	if !seq.Contains([]string{mod.ReviewApproved, mod.ReviewRejected}, options.verdict) {
+		return errors.Errorf("invalid verdict: %s", options.verdict)
+	}
+
+	ws, err := mod.ResolveSumFiles(&mod.ResolveOptions{
+		SumFiles:  args[1:],
+		Workspace: options.workspace,
+		Auto:      options.auto,
+		Log:       log.StandardLogger(),
+	})
+	if err != nil {
+		return err
+	}
+	options.ws = ws
+
+	err = options.Sandbox.AddReadOnlyPath(ws.Paths()...)
+	if err != nil {
+		return err
+	}
+
+	return options.Sandbox.Confine()
+}
+
+func run(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+
+	name, version, ok := strings.Cut(args[0], "@")
+	if !ok {
+		return errors.Errorf("%s: missing version", args[0])
+	}
+
+	keys, err := blob.ReadKeyring(options.PrivKey, options.PubKeys)
+	if err != nil {
+		return err
+	}
+
+	sum, err := mod.ReadGoSum(&mod.SumOptions{
+		SumFiles: options.ws.SumFiles,
+		SigPath:  filepath.Join(options.Config.CacheDir, "mod"),
+		GoPath:   options.GoPath,
+		Origins:  options.Config.Origins,
+		Local:    options.ws.Local,
+		Log:      log.StandardLogger(),
+	})
+	if err != nil {
+		return err
+	}
+
+	src, ok := seq.FindBy(sum.Sources, func(src *mod.Source) bool {
+		return src.Name == name && src.Version == version
+	})
+	if !ok {
+		return errors.Errorf("%s: not in the go.sum file(s)", args[0])
+	}
+
+	review := &mod.Review{
+		Verdict: options.verdict,
+		Notes:   options.notes,
+		Base:    options.base,
+		Time:    time.Now().UTC(),
+	}
+	err = src.WriteReview(review, keys)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("%s", review)
+	log.Infof("successfully wrote %s", src.ReviewPath())
+	return nil
+}
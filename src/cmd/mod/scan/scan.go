@@ -0,0 +1,156 @@
+package scancmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	rootcmd "github.com/illikainen/gofer/src/cmd/root"
+	"github.com/illikainen/gofer/src/config"
+	"github.com/illikainen/gofer/src/mod"
+
+	"github.com/illikainen/go-cryptor/src/blob"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var options struct {
+	*rootcmd.Options
+	suppress  string
+	failOn    string
+	json      bool
+	workspace string
+	auto      bool
+	ws        *mod.Workspace
+}
+
+var command = &cobra.Command{
+	Use:   "scan [flags] [<go.sum>...]",
+	Short: "Scan module zips for suspicious content",
+	Long: "Scan module zips for suspicious content.\n\n" +
+		"The verified zip of every module version in the specified go.sum file(s) is scanned for " +
+		"content that's allowed in a module but that deserves a closer look: binaries, shared " +
+		"objects, executables, nested archives, minified JavaScript, high-entropy blobs, shell " +
+		"scripts, go:generate directives that access the network and large testdata.  Reviewed " +
+		"findings can be suppressed with a TOML file of [[suppress]] tables with module, path, " +
+		"rule and reason keys.",
+	PreRunE: preRun,
+	RunE:    run,
+}
+
+func Command(opts *rootcmd.Options) *cobra.Command {
+	options.Options = opts
+	return command
+}
+
+func init() {
+	flags := command.Flags()
+
+	flags.StringVarP(&options.suppress, "suppress", "s", "", "File with suppressed findings")
+	flags.StringVarP(&options.failOn, "fail-on", "", mod.SeverityHigh,
+		fmt.Sprintf("Fail on unsuppressed findings of this severity or higher (%s)",
+			strings.Join(mod.Severities, ", ")))
+	flags.BoolVarP(&options.json, "json", "", false, "Print the findings as JSON")
+	flags.StringVarP(&options.workspace, "workspace", "w", "",
+		"Include the go.sum files of the go.work workspace in this directory")
+	flags.BoolVarP(&options.auto, "auto", "", false,
+		"Discover the go.sum files of the current module, repository and workspace")
+}
+
+func preRun(_ *cobra.Command, args []string) error {
+	if mod.SeverityRank(options.failOn) < 0 {
+		return errors.Errorf("invalid severity: %s", options.failOn)
+	}
+
+	ws, err := mod.ResolveSumFiles(&mod.ResolveOptions{
+		SumFiles:  args,
+		Workspace: options.workspace,
+		Auto:      options.auto,
+		Log:       log.StandardLogger(),
+	})
+	if err != nil {
+		return err
+	}
+	options.ws = ws
+
+	err = options.Sandbox.AddReadOnlyPath(ws.Paths()...)
+	if err != nil {
+		return err
+	}
+
+	if options.suppress != "" {
+		err = options.Sandbox.AddReadOnlyPath(options.suppress)
+		if err != nil {
+			return err
+		}
+	}
+
+	return options.Sandbox.Confine()
+}
+
+func run(cmd *cobra.Command, _ []string) error {
+	cmd.SilenceUsage = true
+
+	keys, err := blob.ReadKeyring(options.PrivKey, options.PubKeys)
+	if err != nil {
+		return err
+	}
+
+	suppressions := []config.ScanSuppression{}
+	if options.suppress != "" {
+		suppressions, err = config.ReadScanSuppressions(options.suppress)
+		if err != nil {
+			return err
+		}
+	}
+
+	sum, err := mod.ReadGoSum(&mod.SumOptions{
+		SumFiles: options.ws.SumFiles,
+		SigPath:  filepath.Join(options.Config.CacheDir, "mod"),
+		GoPath:   options.GoPath,
+		Origins:  options.Config.Origins,
+		Local:    options.ws.Local,
+		Log:      log.StandardLogger(),
+	})
+	if err != nil {
+		return err
+	}
+
+	findings, err := sum.Scan(keys, suppressions)
+	if err != nil {
+		return err
+	}
+
+	if options.json {
+		data, err := json.MarshalIndent(findings, "", "    ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	}
+
+	failed := 0
+	suppressed := 0
+	for _, finding := range findings {
+		switch {
+		case finding.Suppressed != "":
+			log.Debugf("%s: %s", finding, finding.Suppressed)
+			suppressed++
+		case mod.SeverityRank(finding.Severity) >= mod.SeverityRank(options.failOn):
+			if !options.json {
+				log.Error(finding)
+			}
+			failed++
+		case !options.json:
+			log.Warn(finding)
+		}
+	}
+
+	log.Infof("%d finding(s), %d suppressed", len(findings), suppressed)
+	if failed > 0 {
+		return errors.Errorf("%d finding(s) with severity %s or higher", failed, options.failOn)
+	}
+	return nil
+}
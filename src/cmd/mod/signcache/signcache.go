@@ -2,25 +2,31 @@ package signcachecmd
 
 import (
 	rootcmd "github.com/illikainen/gofer/src/cmd/root"
+	"github.com/illikainen/gofer/src/config"
 	"github.com/illikainen/gofer/src/mod"
 
 	"github.com/illikainen/go-cryptor/src/blob"
 	"github.com/illikainen/go-utils/src/fn"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
 var options struct {
 	*rootcmd.Options
-	output string
+	output    string
+	policy    string
+	base      string
+	workspace string
+	auto      bool
+	ws        *mod.Workspace
 }
 
 var command = &cobra.Command{
-	Use:     "sign-cache [flags] <go.sum>...",
+	Use:     "sign-cache [flags] [<go.sum>...]",
 	Short:   "Verify and sign GOPATH modules and their metadata referenced in the specified go.sum file(s)",
 	PreRunE: modSignCachePreRun,
 	RunE:    modSignCacheRun,
-	Args:    cobra.MinimumNArgs(1),
 }
 
 func Command(opts *rootcmd.Options) *cobra.Command {
@@ -33,10 +39,32 @@ func init() {
 
 	flags.StringVarP(&options.output, "output", "o", "", "Output directory for archived modules")
 	fn.Must(command.MarkFlagRequired("output"))
+
+	flags.StringVarP(&options.policy, "capability-policy", "", "",
+		"Refuse to sign modules that gain capabilities that aren't allowed by this policy file")
+	flags.StringVarP(&options.base, "base", "", "",
+		"go.sum file or git <rev>[:<path>], relative to the checked go.sum, to compare capabilities "+
+			"against (default: none)")
+
+	flags.StringVarP(&options.workspace, "workspace", "w", "",
+		"Include the go.sum files of the go.work workspace in this directory")
+	flags.BoolVarP(&options.auto, "auto", "", false,
+		"Discover the go.sum files of the current module, repository and workspace")
 }
 
 func modSignCachePreRun(_ *cobra.Command, args []string) error {
-	err := options.Sandbox.AddReadOnlyPath(args...)
+	ws, err := mod.ResolveSumFiles(&mod.ResolveOptions{
+		SumFiles:  args,
+		Workspace: options.workspace,
+		Auto:      options.auto,
+		Log:       log.StandardLogger(),
+	})
+	if err != nil {
+		return err
+	}
+	options.ws = ws
+
+	err = options.Sandbox.AddReadOnlyPath(ws.Paths()...)
 	if err != nil {
 		return err
 	}
@@ -46,10 +74,33 @@ func modSignCachePreRun(_ *cobra.Command, args []string) error {
 		return err
 	}
 
+	if options.base != "" && options.policy == "" {
+		return errors.Errorf("--base requires --capability-policy")
+	}
+
+	if options.policy != "" {
+		err = options.Sandbox.AddReadOnlyPath(options.policy)
+		if err != nil {
+			return err
+		}
+	}
+
+	if options.base != "" {
+		paths, err := mod.SumRefPaths(options.base, ws.SumFiles)
+		if err != nil {
+			return err
+		}
+
+		err = options.Sandbox.AddReadOnlyPath(paths...)
+		if err != nil {
+			return err
+		}
+	}
+
 	return options.Sandbox.Confine()
 }
 
-func modSignCacheRun(cmd *cobra.Command, args []string) (err error) {
+func modSignCacheRun(cmd *cobra.Command, _ []string) (err error) {
 	cmd.SilenceUsage = true
 
 	keys, err := blob.ReadKeyring(options.PrivKey, options.PubKeys)
@@ -58,15 +109,59 @@ func modSignCacheRun(cmd *cobra.Command, args []string) (err error) {
 	}
 
 	sum, err := mod.ReadGoSum(&mod.SumOptions{
-		SumFiles: args,
+		SumFiles: options.ws.SumFiles,
 		SigPath:  options.output,
 		GoPath:   options.GoPath,
+		Origins:  options.Config.Origins,
+		Local:    options.ws.Local,
 		Log:      log.StandardLogger(),
 	})
 	if err != nil {
 		return err
 	}
 
+	cooldown, err := mod.NewCooldown(&options.Config.Cooldown)
+	if err != nil {
+		return err
+	}
+
+	if cooldown != nil {
+		err = cooldown.Check(sum)
+		if err != nil {
+			return err
+		}
+	}
+
+	typosquat, err := mod.NewTyposquat(&options.Config.Typosquat, options.output, keys)
+	if err != nil {
+		return err
+	}
+
+	_, err = typosquat.Check(sum)
+	if err != nil {
+		return err
+	}
+
+	if options.policy != "" {
+		err = checkCapabilities(sum, keys)
+		if err != nil {
+			return err
+		}
+	}
+
+	licensePolicy := &options.Config.Licenses
+	if len(licensePolicy.Allow) > 0 || len(licensePolicy.Deny) > 0 {
+		licenses, err := sum.Licenses(keys)
+		if err != nil {
+			return err
+		}
+
+		err = sum.CheckLicenses(licenses, licensePolicy)
+		if err != nil {
+			return err
+		}
+	}
+
 	err = sum.VerifyAndSign(keys)
 	if err != nil {
 		return err
@@ -75,3 +170,43 @@ func modSignCacheRun(cmd *cobra.Command, args []string) (err error) {
 	log.Infof("successfully wrote signed cache to %s", options.output)
 	return nil
 }
+
+func checkCapabilities(sum *mod.SumFile, keys *blob.Keyring) error {
+	policy, err := config.ReadCapabilityPolicy(options.policy)
+	if err != nil {
+		return err
+	}
+
+	var base *mod.SumFile
+	if options.base != "" {
+		base, err = mod.ReadSumRef(options.base, options.ws.SumFiles, &mod.SumOptions{
+			SigPath: options.output,
+			GoPath:  options.GoPath,
+			Origins: options.Config.Origins,
+			Log:     log.StandardLogger(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	changes, err := sum.CapabilityDiff(base, policy, keys)
+	if err != nil {
+		return err
+	}
+
+	denied := 0
+	for _, change := range changes {
+		if len(change.Denied) > 0 {
+			log.Error(change)
+			denied++
+		} else {
+			log.Info(change)
+		}
+	}
+
+	if denied > 0 {
+		return errors.Errorf("%d module(s) gained capabilities that aren't allowed by %s", denied, options.policy)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package sumdbcheckcmd
+
+import (
+	"path/filepath"
+
+	rootcmd "github.com/illikainen/gofer/src/cmd/root"
+	"github.com/illikainen/gofer/src/mod"
+
+	"github.com/illikainen/go-utils/src/seq"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var options struct {
+	*rootcmd.Options
+	sumdb     string
+	url       string
+	snapshot  string
+	workspace string
+	auto      bool
+	ws        *mod.Workspace
+}
+
+var command = &cobra.Command{
+	Use:   "sumdb-check [flags] [<go.sum>...]",
+	Short: "Verify the specified go.sum file(s) against a checksum database",
+	Long: "Verify the specified go.sum file(s) against a checksum database.\n\n" +
+		"Every line is looked up in the transparency log of the checksum database and " +
+		"authenticated with its signed tree head and tile proofs.  The database is accessed " +
+		"through the configured proxy or directly unless --snapshot is given, in which case the " +
+		"lookups and tiles are read from a directory with the layout of " +
+		"$GOPATH/pkg/mod/cache/download/sumdb and the network is never accessed.  The command " +
+		"fails if the log doesn't contain or contradicts any of the lines, and lines without a " +
+		"lookup in the snapshot are reported as missing from the log.",
+	PreRunE: preRun,
+	RunE:    run,
+}
+
+func Command(opts *rootcmd.Options) *cobra.Command {
+	options.Options = opts
+	return command
+}
+
+func init() {
+	flags := command.Flags()
+
+	flags.StringVarP(&options.sumdb, "sumdb", "", "",
+		"Checksum database key or name (default from the configuration or sum.golang.org)")
+	flags.StringVarP(&options.url, "url", "", "",
+		"Checksum database URL (default through the configured proxy or directly)")
+	flags.StringVarP(&options.snapshot, "snapshot", "", "",
+		"Directory with previously retrieved lookups and tiles")
+	flags.StringVarP(&options.workspace, "workspace", "w", "",
+		"Include the go.sum files of the go.work workspace in this directory")
+	flags.BoolVarP(&options.auto, "auto", "", false,
+		"Discover the go.sum files of the current module, repository and workspace")
+}
+
+func preRun(_ *cobra.Command, args []string) error {
+	options.sumdb, _ = seq.Coalesce(options.sumdb, options.Config.SumDB, "sum.golang.org")
+	if options.sumdb == "off" {
+		return errors.Errorf("no checksum database")
+	}
+
+	name, err := mod.SumDBName(options.sumdb)
+	if err != nil {
+		return err
+	}
+
+	if options.snapshot != "" && options.url != "" {
+		return errors.Errorf("--snapshot and --url are mutually exclusive")
+	}
+	if options.snapshot == "" && options.url == "" {
+		options.url = "https://" + name
+		if options.Config.Proxy != "" {
+			proxy, err := mod.NewProxy(options.Config.Proxy)
+			if err != nil {
+				return err
+			}
+			options.url = proxy.SumDBURL(name)
+		}
+	}
+
+	ws, err := mod.ResolveSumFiles(&mod.ResolveOptions{
+		SumFiles:  args,
+		Workspace: options.workspace,
+		Auto:      options.auto,
+		Log:       log.StandardLogger(),
+	})
+	if err != nil {
+		return err
+	}
+	options.ws = ws
+
+	err = options.Sandbox.AddReadOnlyPath(ws.Paths()...)
+	if err != nil {
+		return err
+	}
+
+	if options.snapshot != "" {
+		err = options.Sandbox.AddReadOnlyPath(options.snapshot)
+		if err != nil {
+			return err
+		}
+	} else {
+		// The database host is enforced by mod.Proxy since the sandbox
+		// can only share or unshare the network as a whole.
+		options.Sandbox.SetShareNet(true)
+	}
+
+	return options.Sandbox.Confine()
+}
+
+func run(cmd *cobra.Command, _ []string) error {
+	cmd.SilenceUsage = true
+
+	opts := &mod.SumDBOptions{
+		Key: options.sumdb,
+		Log: log.StandardLogger(),
+	}
+
+	if options.snapshot != "" {
+		opts.CacheDir = options.snapshot
+	} else {
+		conn, err := mod.NewProxy(options.url)
+		if err != nil {
+			return err
+		}
+
+		opts.URL = options.url
+		opts.Client = conn.Client
+		opts.CacheDir = filepath.Join(options.GoPath, "pkg", "mod", "cache", "download", "sumdb")
+		opts.ConfigDir = filepath.Join(options.GoPath, "pkg", "sumdb")
+	}
+
+	db, err := mod.NewSumDB(opts)
+	if err != nil {
+		return err
+	}
+
+	sum, err := mod.ReadGoSum(&mod.SumOptions{
+		SumFiles: options.ws.SumFiles,
+		SigPath:  filepath.Join(options.Config.CacheDir, "mod"),
+		GoPath:   options.GoPath,
+		Origins:  options.Config.Origins,
+		Local:    options.ws.Local,
+		Log:      log.StandardLogger(),
+	})
+	if err != nil {
+		return err
+	}
+
+	failed, err := sum.CheckSumDB(db)
+	if err != nil {
+		return err
+	}
+
+	if len(failed) > 0 {
+		for _, line := range failed {
+			log.Errorf("missing or contradicted: %s", line)
+		}
+		return errors.Errorf("%d go.sum line(s) missing from or contradicted by %s", len(failed), db.Name)
+	}
+
+	log.Infof("successfully verified %d go.sum line(s) against %s",
+		len(sum.Sources)+len(sum.ModFiles), db.Name)
+	return nil
+}
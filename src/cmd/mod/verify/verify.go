@@ -13,15 +13,18 @@ import (
 
 var options struct {
 	*rootcmd.Options
-	input string
+	input     string
+	repair    bool
+	workspace string
+	auto      bool
+	ws        *mod.Workspace
 }
 
 var command = &cobra.Command{
-	Use:     "verify [flags] <go.sum>...",
+	Use:     "verify [flags] [<go.sum>...]",
 	Short:   "Verify modules and metadata referenced in the specified go.sum file(s)",
 	PreRunE: preRun,
 	RunE:    run,
-	Args:    cobra.MinimumNArgs(1),
 }
 
 func Command(opts *rootcmd.Options) *cobra.Command {
@@ -33,10 +36,32 @@ func init() {
 	flags := command.Flags()
 
 	flags.StringVarP(&options.input, "input", "i", "", "Directory with signed modules and metadata")
+	flags.BoolVarP(&options.repair, "repair", "", false,
+		"Replace GOPATH modules and metadata that fail verification with their signed counterparts")
+	flags.StringVarP(&options.workspace, "workspace", "w", "",
+		"Include the go.sum files of the go.work workspace in this directory")
+	flags.BoolVarP(&options.auto, "auto", "", false,
+		"Discover the go.sum files of the current module, repository and workspace")
 }
 
 func preRun(_ *cobra.Command, args []string) error {
-	err := options.Sandbox.AddReadOnlyPath(append([]string{options.input}, args...)...)
+	ws, err := mod.ResolveSumFiles(&mod.ResolveOptions{
+		SumFiles:  args,
+		Workspace: options.workspace,
+		Auto:      options.auto,
+		Log:       log.StandardLogger(),
+	})
+	if err != nil {
+		return err
+	}
+	options.ws = ws
+
+	err = options.Sandbox.AddReadOnlyPath(ws.Paths()...)
+	if err != nil {
+		return err
+	}
+
+	err = options.Sandbox.AddReadOnlyPath(options.input)
 	if err != nil {
 		return err
 	}
@@ -44,7 +69,7 @@ func preRun(_ *cobra.Command, args []string) error {
 	return options.Sandbox.Confine()
 }
 
-func run(cmd *cobra.Command, args []string) (err error) {
+func run(cmd *cobra.Command, _ []string) (err error) {
 	cmd.SilenceUsage = true
 
 	keys, err := blob.ReadKeyring(options.PrivKey, options.PubKeys)
@@ -58,15 +83,25 @@ func run(cmd *cobra.Command, args []string) (err error) {
 	}
 
 	sum, err := mod.ReadGoSum(&mod.SumOptions{
-		SumFiles: args,
+		SumFiles: options.ws.SumFiles,
 		SigPath:  input,
 		GoPath:   options.GoPath,
+		Origins:  options.Config.Origins,
+		Local:    options.ws.Local,
 		Log:      log.StandardLogger(),
 	})
 	if err != nil {
 		return err
 	}
 
+	if options.repair {
+		repaired, err := sum.Repair(keys)
+		if err != nil {
+			return err
+		}
+		log.Infof("repaired %d file(s) and directories in %s", len(repaired), options.GoPath)
+	}
+
 	vr, err := sum.Verify(keys)
 	if err != nil {
 		return err
@@ -77,7 +112,9 @@ func run(cmd *cobra.Command, args []string) (err error) {
 	log.Infof("        %d signed sources", len(vr.SignedSources))
 	log.Infof("        %d signed mod files", len(vr.SignedModFiles))
 	log.Infof("        %d signed info files", len(vr.SignedInfoFiles))
-	if len(vr.SignedFiles) == len(vr.SignedSources)+len(vr.SignedModFiles)+len(vr.SignedInfoFiles) {
+	log.Infof("        %d signed reviews", len(vr.SignedReviews))
+	if len(vr.SignedFiles) == len(vr.SignedSources)+len(vr.SignedModFiles)+len(vr.SignedInfoFiles)+
+		len(vr.SignedReviews) {
 		log.Info("        (all signed files also had their content fully verified)")
 	}
 
@@ -86,5 +123,21 @@ func run(cmd *cobra.Command, args []string) (err error) {
 	log.Infof("    %d Go cache dir sources", len(vr.GoDirSources))
 	log.Infof("    %d Go cache mod files", len(vr.GoModFiles))
 	log.Infof("    %d Go cache info files", len(vr.GoInfoFiles))
+
+	notices, err := sum.CheckRetracted(&options.Config.Retracted, keys)
+	if err != nil {
+		return err
+	}
+	if len(notices) > 0 {
+		log.Infof("\n%d retracted or deprecated module version(s)", len(notices))
+	}
+
+	if options.Config.Review.RequireAll {
+		reviews, err := sum.CheckReviews(&options.Config.Review, keys)
+		if err != nil {
+			return err
+		}
+		log.Infof("\nsuccessfully verified %d approved review(s)", len(reviews))
+	}
 	return nil
 }
@@ -0,0 +1,168 @@
+package vulncmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	rootcmd "github.com/illikainen/gofer/src/cmd/root"
+	"github.com/illikainen/gofer/src/mod"
+
+	"github.com/illikainen/go-utils/src/fn"
+	"github.com/illikainen/go-utils/src/iofs"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var options struct {
+	*rootcmd.Options
+	db        string
+	symbols   bool
+	json      bool
+	workspace string
+	auto      bool
+	ws        *mod.Workspace
+}
+
+var command = &cobra.Command{
+	Use:   "vuln [flags] [<go.sum>...]",
+	Short: "Match the modules in the specified go.sum file(s) against a local vulnerability database",
+	Long: "Match the modules in the specified go.sum file(s) against a local vulnerability database.\n\n" +
+		"The database is a directory or zip file with OSV entries, e.g. a copy of " +
+		"https://vuln.go.dev, and the network is never accessed.  The fixed version of every " +
+		"vulnerability is reported together with whether it has already been signed.  With " +
+		"--symbols, the current module and the modules that are extracted to GOPATH are searched " +
+		"for uses of the affected symbols.  The command fails if any vulnerability is found.",
+	PreRunE: preRun,
+	RunE:    run,
+}
+
+func Command(opts *rootcmd.Options) *cobra.Command {
+	options.Options = opts
+	return command
+}
+
+func init() {
+	flags := command.Flags()
+
+	flags.StringVarP(&options.db, "db", "", "", "Directory or zip file with the vulnerability database")
+	fn.Must(command.MarkFlagRequired("db"))
+
+	flags.BoolVarP(&options.symbols, "symbols", "", false, "Search for uses of the affected symbols")
+	flags.BoolVarP(&options.json, "json", "", false, "Print the vulnerabilities as JSON")
+	flags.StringVarP(&options.workspace, "workspace", "w", "",
+		"Include the go.sum files of the go.work workspace in this directory")
+	flags.BoolVarP(&options.auto, "auto", "", false,
+		"Discover the go.sum files of the current module, repository and workspace")
+}
+
+func preRun(_ *cobra.Command, args []string) error {
+	ws, err := mod.ResolveSumFiles(&mod.ResolveOptions{
+		SumFiles:  args,
+		Workspace: options.workspace,
+		Auto:      options.auto,
+		Log:       log.StandardLogger(),
+	})
+	if err != nil {
+		return err
+	}
+	options.ws = ws
+
+	err = options.Sandbox.AddReadOnlyPath(ws.Paths()...)
+	if err != nil {
+		return err
+	}
+
+	err = options.Sandbox.AddReadOnlyPath(options.db)
+	if err != nil {
+		return err
+	}
+
+	if options.symbols {
+		err = options.Sandbox.AddReadOnlyPath(".")
+		if err != nil {
+			return err
+		}
+	}
+
+	return options.Sandbox.Confine()
+}
+
+func run(cmd *cobra.Command, _ []string) error {
+	cmd.SilenceUsage = true
+
+	entries, err := mod.ReadVulnDB(options.db)
+	if err != nil {
+		return err
+	}
+	log.Debugf("read %d vulnerability entries from %s", len(entries), options.db)
+
+	sum, err := mod.ReadGoSum(&mod.SumOptions{
+		SumFiles: options.ws.SumFiles,
+		SigPath:  filepath.Join(options.Config.CacheDir, "mod"),
+		GoPath:   options.GoPath,
+		Origins:  options.Config.Origins,
+		Local:    options.ws.Local,
+		Log:      log.StandardLogger(),
+	})
+	if err != nil {
+		return err
+	}
+
+	vulns, err := sum.Vulns(entries)
+	if err != nil {
+		return err
+	}
+
+	if options.symbols && len(vulns) > 0 {
+		dirs := []string{"."}
+		for _, src := range sum.Sources {
+			exists, err := iofs.Exists(src.DirPath())
+			if err != nil {
+				return err
+			}
+			if !exists {
+				continue
+			}
+
+			err = src.Verify(src.DirPath(), mod.DirMode)
+			if err != nil {
+				return err
+			}
+			dirs = append(dirs, src.DirPath())
+		}
+
+		for _, v := range vulns {
+			err := v.FindReferences(dirs)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if options.json {
+		data, err := json.MarshalIndent(vulns, "", "    ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	} else {
+		for _, v := range vulns {
+			log.Error(v)
+			for _, ref := range v.References {
+				log.Errorf("    %s", ref)
+			}
+			if options.symbols && len(v.References) == 0 {
+				log.Infof("    no uses of the affected symbols were found")
+			}
+		}
+	}
+
+	if len(vulns) > 0 {
+		return errors.Errorf("found %d vulnerabilities", len(vulns))
+	}
+
+	log.Infof("no vulnerabilities found in %d entries", len(entries))
+	return nil
+}
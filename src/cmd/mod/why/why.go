@@ -0,0 +1,131 @@
+package whycmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	rootcmd "github.com/illikainen/gofer/src/cmd/root"
+	"github.com/illikainen/gofer/src/mod"
+
+	"github.com/illikainen/go-cryptor/src/blob"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"golang.org/x/mod/module"
+)
+
+var options struct {
+	*rootcmd.Options
+	modFiles  []string
+	workspace string
+	auto      bool
+	ws        *mod.Workspace
+}
+
+var command = &cobra.Command{
+	Use:   "why [flags] <module>[@<version>] [<go.sum>...]",
+	Short: "Show how a module is reached from the main module(s)",
+	Long: "Show how a module is reached from the main module(s).\n\n" +
+		"The shortest requirement path is computed from .mod files that are verified against the " +
+		"specified go.sum file(s), without invoking the Go command or accessing the network.",
+	Args:    cobra.MinimumNArgs(1),
+	PreRunE: preRun,
+	RunE:    run,
+}
+
+func Command(opts *rootcmd.Options) *cobra.Command {
+	options.Options = opts
+	return command
+}
+
+func init() {
+	flags := command.Flags()
+
+	flags.StringSliceVarP(&options.modFiles, "modfile", "m", []string{"go.mod"}, "go.mod for the main module(s)")
+	flags.StringVarP(&options.workspace, "workspace", "w", "",
+		"Include the go.sum files of the go.work workspace in this directory")
+	flags.BoolVarP(&options.auto, "auto", "", false,
+		"Discover the go.sum files of the current module, repository and workspace")
+}
+
+func preRun(_ *cobra.Command, args []string) error {
+	ws, err := mod.ResolveSumFiles(&mod.ResolveOptions{
+		SumFiles:  args[1:],
+		Workspace: options.workspace,
+		Auto:      options.auto,
+		Log:       log.StandardLogger(),
+	})
+	if err != nil {
+		return err
+	}
+	options.ws = ws
+
+	err = options.Sandbox.AddReadOnlyPath(ws.Paths()...)
+	if err != nil {
+		return err
+	}
+
+	err = options.Sandbox.AddReadOnlyPath(options.modFiles...)
+	if err != nil {
+		return err
+	}
+
+	return options.Sandbox.Confine()
+}
+
+func run(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+
+	path, version, _ := strings.Cut(args[0], "@")
+	target := module.Version{Path: path, Version: version}
+
+	keys, err := blob.ReadKeyring(options.PrivKey, options.PubKeys)
+	if err != nil {
+		return err
+	}
+
+	sum, err := mod.ReadGoSum(&mod.SumOptions{
+		SumFiles: options.ws.SumFiles,
+		SigPath:  filepath.Join(options.Config.CacheDir, "mod"),
+		GoPath:   options.GoPath,
+		Origins:  options.Config.Origins,
+		Local:    options.ws.Local,
+		Log:      log.StandardLogger(),
+	})
+	if err != nil {
+		return err
+	}
+
+	graph, err := sum.Graph(&mod.GraphOptions{
+		ModFiles: options.modFiles,
+		Keyring:  keys,
+	})
+	if err != nil {
+		return err
+	}
+
+	chain := graph.Why(target)
+	if chain == nil {
+		return errors.Errorf("%s: not reachable from the main module(s)", args[0])
+	}
+
+	fmt.Printf("# %s\n", args[0])
+	for _, m := range chain {
+		fmt.Println(m)
+	}
+
+	selected := false
+	for _, m := range graph.BuildList {
+		if m.Path == target.Path {
+			selected = true
+			if m.Version != chain[len(chain)-1].Version {
+				log.Infof("%s: selected version is %s", target.Path, m.Version)
+			}
+		}
+	}
+	if !selected {
+		log.Warnf("%s: not in the build list", target.Path)
+	}
+	return nil
+}
@@ -22,9 +22,101 @@ type Config struct {
 	CacheDir  string
 	GoPath    string
 	GoCache   string
+	Mirror    string // directory laid out according to the GOPROXY protocol, e.g. a mirror of the module cache
+	Proxy     string // upstream GOPROXY URL for mod fetch-upstream, e.g. https://proxy.golang.org
+	SumDB     string // checksum database key for mod fetch-upstream, e.g. sum.golang.org
+	Review    ReviewPolicy
+	Cooldown  CooldownPolicy
+	Licenses  LicensePolicy
+	Typosquat TyposquatPolicy
+	Retracted RetractedPolicy
+	Origins   map[string]string // module path prefix to URL template, e.g. "https://github.com/org/{1}"
 	Profiles  map[string]Config `toml:"profile"`
 }
 
+type ReviewPolicy struct {
+	RequireAll bool     `toml:"require_all"` // require an approved review for every version in go.sum
+	Reviewers  []string // fingerprints of the keys that may sign reviews, any trusted key is accepted if empty
+	Exempt     []string // module path prefixes that don't require a review, e.g. golang.org/x
+}
+
+type CooldownPolicy struct {
+	MinAge string   `toml:"min_age"` // e.g. 7d, versions published more recently are refused
+	Allow  []string // module paths or path@version exempt from the cooldown, e.g. for security fixes
+}
+
+type TyposquatPolicy struct {
+	Fail        bool     // fail instead of warning about lookalike module paths
+	MaxDistance int      `toml:"max_distance"` // edit distance to trusted module paths, 1 if unset
+	Popular     []string // trusted module paths in addition to those in the signature directory
+	Allow       []string // module paths that are known not to be lookalikes
+}
+
+type RetractedPolicy struct {
+	Fail  bool     // fail instead of warning about retracted versions and deprecated modules
+	Allow []string // module paths or path@version that may be retracted or deprecated
+}
+
+type LicensePolicy struct {
+	Allow  []string // SPDX identifiers, every license that isn't denied is allowed if empty
+	Deny   []string // SPDX identifiers, "unknown" denies modules without a recognized license
+	Exempt []string // module paths or path@version, e.g. after a legal review
+}
+
+// Capabilities that modules may gain in an upgrade, see mod.Capabilities.
+// The policy is read from a separate file so that it can be checked in and
+// reviewed together with go.sum.
+type CapabilityPolicy struct {
+	Allow   []string            // capabilities that every module may gain
+	Modules map[string][]string // capabilities that specific modules may gain, by module path prefix
+}
+
+func ReadCapabilityPolicy(path string) (*CapabilityPolicy, error) {
+	p := &CapabilityPolicy{}
+	meta, err := toml.DecodeFile(path, p)
+	if err != nil {
+		return nil, err
+	}
+
+	undecoded := meta.Undecoded()
+	if len(undecoded) > 0 {
+		return nil, errors.Errorf("%s: unknown key: %s", path, undecoded[0])
+	}
+	return p, nil
+}
+
+// Findings of `mod scan` that have been reviewed and accepted.
+type ScanSuppression struct {
+	Module string // module path or path@version, every module if empty
+
+	// Path glob in the module.  Globs without a separator match the base
+	// name and globs that end with a separator match a directory prefix.
+	Path string
+
+	Rule   string // every rule if empty
+	Reason string
+}
+
+func ReadScanSuppressions(path string) ([]ScanSuppression, error) {
+	s := &struct{ Suppress []ScanSuppression }{}
+	meta, err := toml.DecodeFile(path, s)
+	if err != nil {
+		return nil, err
+	}
+
+	undecoded := meta.Undecoded()
+	if len(undecoded) > 0 {
+		return nil, errors.Errorf("%s: unknown key: %s", path, undecoded[0])
+	}
+
+	for _, suppression := range s.Suppress {
+		if suppression.Path == "" && suppression.Rule == "" {
+			return nil, errors.Errorf("%s: suppressions require a path or a rule", path)
+		}
+	}
+	return s.Suppress, nil
+}
+
 func Read(path string, overrides *Config) (*Config, error) {
 	cache, err := os.UserCacheDir()
 	if err != nil {
@@ -74,3 +74,42 @@ func (g *Git) CommitDate(obj string) (int64, error) {
 
 	return date, nil
 }
+
+// Read the content of path, relative to Dir, at obj.
+func (g *Git) Show(obj string, path string) ([]byte, error) {
+	err := validateObj(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := process.Exec(&process.ExecOptions{
+		Command: []string{"git", "-C", g.Dir, "show", obj + ":./" + path},
+		Stdout:  process.CaptureOutput,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return out.Stdout, nil
+}
+
+// Report whether path, relative to the repository root, exists at obj.
+func (g *Git) Exists(obj string, path string) bool {
+	if validateObj(obj) != nil {
+		return false
+	}
+
+	_, err := process.Exec(&process.ExecOptions{
+		Command: []string{"git", "-C", g.Dir, "cat-file", "-e", obj + ":" + path},
+	})
+	return err == nil
+}
+
+// Objects are often given by the user and they'd be parsed as options by
+// git if they start with a dash.
+func validateObj(obj string) error {
+	if strings.HasPrefix(obj, "-") {
+		return errors.Errorf("invalid git object: %s", obj)
+	}
+	return nil
+}
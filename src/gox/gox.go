@@ -1,7 +1,10 @@
 package gox
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"strings"
@@ -9,11 +12,13 @@ import (
 	"github.com/illikainen/gofer/src/metadata"
 
 	"github.com/illikainen/go-utils/src/process"
+	"github.com/pkg/errors"
 )
 
 type Options struct {
 	Dir     string
 	Flags   []string
+	Env     []string // additional environment variables
 	Release bool
 }
 
@@ -23,7 +28,7 @@ type Go struct {
 }
 
 func New(opts *Options) *Go {
-	return &Go{
+	g := &Go{
 		Options: opts,
 		env: append(
 			os.Environ(),
@@ -35,6 +40,8 @@ func New(opts *Options) *Go {
 			fmt.Sprintf("%s_RELEASE=%v", strings.ToUpper(metadata.Name()), opts.Release),
 		),
 	}
+	g.env = append(g.env, opts.Env...)
+	return g
 }
 
 func (g *Go) Generate(target string) error {
@@ -63,6 +70,56 @@ func (g *Go) Build(output string, goos string, goarch string) error {
 	return err
 }
 
+// Subset of the package information printed by `go list -json`.
+type Package struct {
+	Dir            string
+	ImportPath     string
+	Name           string
+	GoFiles        []string
+	CgoFiles       []string
+	SFiles         []string
+	IgnoredGoFiles []string
+	Imports        []string
+	Error          *PackageError
+}
+
+type PackageError struct {
+	Pos string
+	Err string
+}
+
+// List the packages matching the patterns.  Packages that fail to load
+// are returned with Error set.
+func (g *Go) List(patterns ...string) ([]*Package, error) {
+	out, err := process.Exec(&process.ExecOptions{
+		Command: append([]string{"go", "list", "-e", "-json"}, patterns...),
+		Env:     g.env,
+		Dir:     g.Dir,
+		Stdout:  process.CaptureOutput,
+		Stderr:  process.LogrusOutput,
+		Trusted: true, // the JSON is indented with tabs and control characters in strings are escaped
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pkgs := []*Package{}
+	decoder := json.NewDecoder(bytes.NewReader(out.Stdout))
+	for {
+		pkg := &Package{}
+		err := decoder.Decode(pkg)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		pkgs = append(pkgs, pkg)
+	}
+
+	return pkgs, nil
+}
+
 func GoPath() (string, error) {
 	cmd := exec.Command("go", "env", "GOPATH")
 	out, err := cmd.Output()
@@ -1,10 +1,11 @@
 package h1
 
 import (
+	"bytes"
 	"fmt"
 	"io"
-	"os"
 
+	"github.com/illikainen/go-utils/src/iofs"
 	"github.com/pkg/errors"
 	"golang.org/x/mod/sumdb/dirhash"
 )
@@ -23,7 +24,17 @@ func VerifyMod(file string, cksum string) error {
 }
 
 func HashMod(file string) (h1 string, err error) {
-	cksum, err := hashFile(file)
+	data, err := iofs.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+
+	return HashModData(data)
+}
+
+// HashModData computes the H1 of the content of a go.mod file.
+func HashModData(data []byte) (h1 string, err error) {
+	cksum, err := hashReader(bytes.NewReader(data), int64(len(data)))
 	if err != nil {
 		return "", err
 	}
@@ -34,7 +45,7 @@ func HashMod(file string) (h1 string, err error) {
 	}
 
 	upstreamH1, err := dirhash.Hash1([]string{"go.mod"}, func(_ string) (io.ReadCloser, error) {
-		return os.Open(file) // #nosec G304
+		return io.NopCloser(bytes.NewReader(data)), nil
 	})
 	if err != nil {
 		return "", err
@@ -3,6 +3,7 @@ package h1
 import (
 	"archive/zip"
 	"fmt"
+	"io"
 	"sort"
 
 	"github.com/illikainen/go-utils/src/errorx"
@@ -30,6 +31,62 @@ func HashZip(file string) (cksum string, err error) {
 	}
 	defer errorx.Defer(z.Close, &err)
 
+	h1, err := hashZip(&z.Reader)
+	if err != nil {
+		return "", err
+	}
+
+	upstreamH1, err := dirhash.HashZip(file, dirhash.Hash1)
+	if err != nil {
+		return "", err
+	}
+	if upstreamH1 != h1 {
+		return "", errors.Errorf("bug")
+	}
+
+	return h1, nil
+}
+
+// HashZipReader computes the H1 of a zip archive that's accessible through
+// r, e.g. a zip that has been read into memory so that the verified bytes
+// are the ones that are used.
+func HashZipReader(r io.ReaderAt, size int64) (string, error) {
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return "", err
+	}
+
+	h1, err := hashZip(z)
+	if err != nil {
+		return "", err
+	}
+
+	// This mirrors dirhash.HashZip() for an already opened archive.
+	names := []string{}
+	files := map[string]*zip.File{}
+	for _, f := range z.File {
+		names = append(names, f.Name)
+		files[f.Name] = f
+	}
+
+	upstreamH1, err := dirhash.Hash1(names, func(name string) (io.ReadCloser, error) {
+		f, ok := files[name]
+		if !ok {
+			return nil, errors.Errorf("%s: not found", name)
+		}
+		return f.Open()
+	})
+	if err != nil {
+		return "", err
+	}
+	if upstreamH1 != h1 {
+		return "", errors.Errorf("bug")
+	}
+
+	return h1, nil
+}
+
+func hashZip(z *zip.Reader) (string, error) {
 	if z.Comment != "" {
 		return "", errors.Errorf("invalid zip comment")
 	}
@@ -95,12 +152,7 @@ func HashZip(file string) (cksum string, err error) {
 	if err != nil {
 		return "", err
 	}
-
-	upstreamH1, err := dirhash.HashZip(file, dirhash.Hash1)
-	if err != nil {
-		return "", err
-	}
-	if len(h1) != 47 || upstreamH1 != h1 {
+	if len(h1) != 47 {
 		return "", errors.Errorf("bug")
 	}
 
@@ -0,0 +1,53 @@
+package h1
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/sumdb/dirhash"
+	modzip "golang.org/x/mod/zip"
+)
+
+func TestHashZipReader(t *testing.T) {
+	dir := t.TempDir()
+	for file, data := range map[string]string{"go.mod": "module example.com/m\n", "m.go": "package m\n"} {
+		err := os.WriteFile(filepath.Join(dir, file), []byte(data), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	err := modzip.CreateFromDir(buf, module.Version{Path: "example.com/m", Version: "v1.0.0"}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zipPath := filepath.Join(t.TempDir(), "m.zip")
+	err = os.WriteFile(zipPath, buf.Bytes(), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := dirhash.HashZip(zipPath, dirhash.Hash1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fromFile, err := HashZip(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fromReader, err := HashZipReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fromFile != want || fromReader != want {
+		t.Fatalf("HashZip() = %s, HashZipReader() = %s, want %s", fromFile, fromReader, want)
+	}
+}
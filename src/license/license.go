@@ -0,0 +1,131 @@
+package license
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/illikainen/go-utils/src/seq"
+	"github.com/pkg/errors"
+)
+
+const Unknown = "unknown"
+
+type license struct {
+	id      string
+	phrases []string
+}
+
+//go:embed corpus.txt
+var corpusBytes []byte
+
+var corpus []*license
+
+func init() {
+	var err error
+	corpus, err = parseCorpus(corpusBytes)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func parseCorpus(data []byte) ([]*license, error) {
+	licenses := []*license{}
+
+	scan := bufio.NewScanner(bytes.NewReader(data))
+	for scan.Scan() {
+		line := strings.TrimSpace(scan.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			licenses = append(licenses, &license{id: line[1 : len(line)-1]})
+		case len(licenses) == 0 || normalize(line) != line:
+			return nil, errors.Errorf("invalid license phrase: %s", line)
+		default:
+			l := licenses[len(licenses)-1]
+			l.phrases = append(l.phrases, line)
+		}
+	}
+
+	return licenses, scan.Err()
+}
+
+// Every SPDX identifier in the corpus.
+func IDs() []string {
+	ids := []string{}
+	for _, l := range corpus {
+		ids = append(ids, l.id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Whether a file name is conventionally used for license texts.
+func IsLicenseFile(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	for _, other := range []string{
+		".go", ".json", ".yml", ".yaml", ".toml", ".xml", ".html", ".js", ".sh", ".py", ".csv", ".png",
+	} {
+		if ext == other {
+			return false
+		}
+	}
+
+	base := strings.ToUpper(path.Base(name))
+	for _, prefix := range []string{"LICENSE", "LICENCE", "COPYING", "UNLICENSE"} {
+		if strings.HasPrefix(base, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Classify a license text by the SPDX identifiers of every license in it,
+// e.g. both licenses of a dual-licensed file.  Unknown is returned if the
+// text doesn't match any license in the corpus.
+func Classify(data []byte) []string {
+	text := normalize(string(data))
+
+	licenses := append([]*license{}, corpus...)
+	sort.SliceStable(licenses, func(i int, j int) bool {
+		return len(licenses[i].phrases) > len(licenses[j].phrases)
+	})
+
+	ids := []string{}
+	for _, l := range licenses {
+		// The phrases of a match are removed so that a license isn't also
+		// reported as the licenses it extends, e.g. ISC as 0BSD, and it's
+		// repeated for every copy of the license.  Normalized text doesn't
+		// contain newlines so they can't be part of a phrase.
+		for l.match(text) {
+			for _, phrase := range l.phrases {
+				text = strings.Replace(text, phrase, "\n", 1)
+			}
+			ids = append(ids, l.id)
+		}
+	}
+
+	if len(ids) == 0 {
+		return []string{Unknown}
+	}
+	ids = seq.Uniq(ids)
+	sort.Strings(ids)
+	return ids
+}
+
+func (l *license) match(text string) bool {
+	return len(l.phrases) > 0 && !seq.ContainsBy(l.phrases, func(phrase string) bool {
+		return !strings.Contains(text, phrase)
+	})
+}
+
+func normalize(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
@@ -0,0 +1,72 @@
+package license
+
+import (
+	"strings"
+	"testing"
+)
+
+const mitText = `Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+and associated documentation files (the "Software"), to deal in the Software without restriction.
+
+The above copyright notice and this permission notice shall be included in all copies or substantial
+portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED.
+`
+
+const gpl3Text = `GNU GENERAL PUBLIC LICENSE
+Version 3, 29 June 2007
+
+Everyone is permitted to copy and distribute verbatim copies of this license document, but changing
+it is not allowed.
+`
+
+const iscText = `Permission to use, copy, modify, and/or distribute this software for any purpose with or
+without fee is hereby granted, provided that the above copyright notice and this permission notice
+appear in all copies.
+
+THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH REGARD TO THIS SOFTWARE.
+`
+
+const bsd3Text = `Redistribution and use in source and binary forms, with or without modification, are
+permitted provided that the following conditions are met:
+
+1. Redistributions of source code must retain the above copyright notice.
+2. Redistributions in binary form must reproduce the above copyright notice.
+3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse
+   or promote products derived from this software without specific prior written permission.
+`
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", []string{Unknown}},
+		{"all rights reserved", []string{Unknown}},
+		{mitText, []string{"MIT"}},
+		{gpl3Text, []string{"GPL-3.0-only"}},
+		{gpl3Text + "\n" + mitText, []string{"GPL-3.0-only", "MIT"}},
+		{mitText + "\n" + gpl3Text, []string{"GPL-3.0-only", "MIT"}},
+		{iscText, []string{"ISC"}},
+		{bsd3Text, []string{"BSD-3-Clause"}},
+		{bsd3Text + "\n" + bsd3Text, []string{"BSD-3-Clause"}},
+	}
+
+	for _, test := range tests {
+		got := Classify([]byte(test.text))
+		if strings.Join(got, " ") != strings.Join(test.want, " ") {
+			t.Errorf("Classify(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+func TestIDs(t *testing.T) {
+	for _, id := range IDs() {
+		for _, deprecated := range []string{"GPL-2.0", "GPL-3.0", "LGPL-2.1", "LGPL-3.0", "AGPL-3.0"} {
+			if id == deprecated {
+				t.Errorf("deprecated SPDX identifier: %s", id)
+			}
+		}
+	}
+}
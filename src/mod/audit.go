@@ -0,0 +1,410 @@
+package mod
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/illikainen/go-utils/src/errorx"
+	"github.com/illikainen/go-utils/src/iofs"
+	"github.com/illikainen/go-utils/src/seq"
+	"github.com/pkg/errors"
+	"golang.org/x/mod/module"
+)
+
+const (
+	AuditVerified     = "verified"
+	AuditUnreferenced = "unreferenced"
+	AuditMismatch     = "mismatch"
+)
+
+type AuditEntry struct {
+	Path        string
+	Name        string
+	Version     string
+	Status      string
+	Reason      string `json:",omitempty"`
+	Quarantined string `json:",omitempty"`
+}
+
+func (a *AuditEntry) Suspicious() bool {
+	return a.Status != AuditVerified
+}
+
+func (a *AuditEntry) mismatch(err error) *AuditEntry {
+	a.Status = AuditMismatch
+	a.Reason = err.Error()
+	return a
+}
+
+func (a *AuditEntry) String() string {
+	if a.Name == "" {
+		return a.Path
+	}
+	return fmt.Sprintf("%s@%s (%s)", a.Name, a.Version, a.Path)
+}
+
+// Walk the Go module cache and classify every module file and extracted
+// module directory.  Unlike Verify(), this also finds entries that aren't
+// referenced by any go.sum even though Go would happily use them.
+func (s *SumFile) AuditCache() ([]*AuditEntry, error) {
+	entries := []*AuditEntry{}
+
+	// The .mod files must be verified before the .info files of their
+	// requirements can be classified, so the result is kept until the
+	// .mod files themselves are audited.
+	modErrs := map[string]error{}
+	for _, m := range s.ModFiles {
+		modErrs[m.ModPath()] = m.Verify(m.ModPath())
+	}
+
+	download := filepath.Join(s.goPath, "pkg", "mod", "cache", "download")
+	err := filepath.WalkDir(download, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) && path == download {
+				return filepath.SkipDir
+			}
+			return err
+		}
+
+		if !d.IsDir() || d.Name() != "@v" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(download, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+
+		name, err := module.UnescapePath(filepath.ToSlash(rel))
+		if err != nil {
+			entries = append(entries, &AuditEntry{
+				Path:   path,
+				Status: AuditUnreferenced,
+				Reason: err.Error(),
+			})
+			return filepath.SkipDir
+		}
+
+		files, err := os.ReadDir(path)
+		if err != nil {
+			return err
+		}
+
+		for _, f := range files {
+			entry := s.auditFile(name, filepath.Join(path, f.Name()), modErrs)
+			if entry != nil {
+				entries = append(entries, entry)
+			}
+		}
+
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	root := filepath.Join(s.goPath, "pkg", "mod")
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) && path == root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+
+		if !d.IsDir() || path == root {
+			return nil
+		}
+
+		if path == filepath.Join(root, "cache") {
+			return filepath.SkipDir
+		}
+
+		if !strings.Contains(d.Name(), "@") {
+			return nil
+		}
+
+		entries = append(entries, s.auditDir(root, path))
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i int, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+	return entries, nil
+}
+
+func (s *SumFile) auditFile(name string, path string, modErrs map[string]error) *AuditEntry {
+	base := filepath.Base(path)
+	if base == "list" || strings.HasSuffix(base, ".lock") {
+		return nil
+	}
+
+	entry := &AuditEntry{
+		Path:   path,
+		Name:   name,
+		Status: AuditUnreferenced,
+	}
+
+	ext := filepath.Ext(base)
+	escaped := strings.TrimSuffix(base, ext)
+	version, err := module.UnescapeVersion(escaped)
+	if err != nil || !seq.Contains([]string{".zip", ".ziphash", ".mod", ".info"}, ext) {
+		entry.Reason = "unknown file"
+		return entry
+	}
+	entry.Version = version
+
+	switch ext {
+	case ".zip":
+		src, ok := seq.FindBy(s.Sources, func(src *Source) bool {
+			return src.Name == name && src.Version == version
+		})
+		if !ok {
+			return entry
+		}
+
+		err = src.Verify(path, ZipMode)
+		if err != nil {
+			return entry.mismatch(err)
+		}
+	case ".ziphash":
+		src, ok := seq.FindBy(s.Sources, func(src *Source) bool {
+			return src.Name == name && src.Version == version
+		})
+		if !ok {
+			return entry
+		}
+
+		data, err := iofs.ReadFile(path)
+		if err != nil {
+			return entry.mismatch(err)
+		}
+
+		if !bytes.Equal(data, []byte(src.Checksum)) {
+			return entry.mismatch(errors.Errorf("%s != %s", data, src.Checksum))
+		}
+	case ".mod":
+		m, ok := seq.FindBy(s.ModFiles, func(m *ModFile) bool {
+			return m.Name == name && m.Version == version
+		})
+		if !ok {
+			return entry
+		}
+
+		err, ok = modErrs[path]
+		if !ok {
+			err = m.Verify(path)
+		}
+		if err != nil {
+			return entry.mismatch(err)
+		}
+	case ".info":
+		// The .info files aren't pinned by hash, so they're considered
+		// to be referenced if the go.sum mentions the module version or
+		// if it's required by a verified .mod file.
+		referenced := seq.ContainsBy(s.ModFiles, func(m *ModFile) bool {
+			return m.Name == name && m.Version == version ||
+				seq.ContainsBy(m.InfoFiles, func(i *InfoFile) bool {
+					return i.Name == name && i.Version == version
+				})
+		}) || seq.ContainsBy(s.Sources, func(src *Source) bool {
+			return src.Name == name && src.Version == version
+		})
+		if !referenced {
+			return entry
+		}
+
+		info := s.newInfoFile(name, version)
+		err = info.Verify(path)
+		if err != nil {
+			return entry.mismatch(err)
+		}
+	}
+
+	entry.Status = AuditVerified
+	return entry
+}
+
+func (s *SumFile) auditDir(root string, path string) *AuditEntry {
+	entry := &AuditEntry{
+		Path:   path,
+		Status: AuditUnreferenced,
+	}
+
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		entry.Reason = err.Error()
+		return entry
+	}
+
+	elts := strings.SplitN(filepath.ToSlash(rel), "@", 2)
+	name, err := module.UnescapePath(elts[0])
+	if err != nil {
+		entry.Reason = err.Error()
+		return entry
+	}
+	entry.Name = name
+
+	version, err := module.UnescapeVersion(elts[1])
+	if err != nil {
+		entry.Reason = err.Error()
+		return entry
+	}
+	entry.Version = version
+
+	src, ok := seq.FindBy(s.Sources, func(src *Source) bool {
+		return src.Name == name && src.Version == version
+	})
+	if !ok {
+		return entry
+	}
+
+	err = src.Verify(path, DirMode)
+	if err != nil {
+		return entry.mismatch(err)
+	}
+
+	entry.Status = AuditVerified
+	return entry
+}
+
+// Move every suspicious entry from GOPATH to a new subdirectory in dir and
+// write a JSON report that lists where each entry came from.  The
+// subdirectory is returned.
+func (s *SumFile) Quarantine(entries []*AuditEntry, dir string) (string, error) {
+	dst := filepath.Join(dir, time.Now().UTC().Format("20060102T150405Z"))
+	err := os.MkdirAll(dst, 0700)
+	if err != nil {
+		return "", err
+	}
+
+	quarantined := []*AuditEntry{}
+	for _, entry := range entries {
+		if !entry.Suspicious() {
+			continue
+		}
+
+		rel, err := filepath.Rel(s.goPath, entry.Path)
+		if err != nil {
+			return "", err
+		}
+		if strings.HasPrefix(rel, "..") {
+			return "", errors.Errorf("%s: not in %s", entry.Path, s.goPath)
+		}
+
+		target := filepath.Join(dst, rel)
+		err = os.MkdirAll(filepath.Dir(target), 0700)
+		if err != nil {
+			return "", err
+		}
+
+		// Extracted modules are read-only, but moving a directory to a
+		// new parent requires write access to the directory itself.
+		stat, err := os.Stat(entry.Path)
+		if err != nil {
+			return "", err
+		}
+		if stat.IsDir() {
+			err := os.Chmod(entry.Path, stat.Mode().Perm()|0200)
+			if err != nil {
+				return "", err
+			}
+		}
+
+		err = moveEntry(entry.Path, target)
+		if err != nil {
+			return "", err
+		}
+
+		entry.Quarantined = target
+		quarantined = append(quarantined, entry)
+		s.log.Infof("%s: moved to %s", entry.Path, target)
+	}
+
+	report, err := json.MarshalIndent(quarantined, "", "    ")
+	if err != nil {
+		return "", err
+	}
+
+	err = writeFile(filepath.Join(dst, "report.json"), append(report, '\n'))
+	if err != nil {
+		return "", err
+	}
+
+	return dst, nil
+}
+
+// Move a file or directory.  The quarantine directory may be on another
+// filesystem than GOPATH, in which case os.Rename() fails with EXDEV and the
+// entry is copied and then removed instead.
+func moveEntry(src string, dst string) error {
+	err := os.Rename(src, dst)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	err = copyEntry(src, dst)
+	if err != nil {
+		return errorx.Join(err, removeReadOnlyDir(dst))
+	}
+
+	return removeReadOnlyDir(src)
+}
+
+func copyEntry(src string, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, 0700)
+		case info.Mode().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		default:
+			return errors.Errorf("%s: unsupported file type", path)
+		}
+	})
+}
+
+func copyFile(src string, dst string, perm fs.FileMode) (err error) {
+	in, err := os.Open(src) // #nosec G304
+	if err != nil {
+		return err
+	}
+	defer errorx.Defer(in.Close, &err)
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm) // #nosec G304
+	if err != nil {
+		return err
+	}
+	defer errorx.Defer(out.Close, &err)
+
+	_, err = io.Copy(out, in)
+	return err
+}
@@ -0,0 +1,372 @@
+package mod
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/illikainen/gofer/src/gox"
+
+	"github.com/illikainen/go-utils/src/iofs"
+	"github.com/illikainen/go-utils/src/seq"
+	"github.com/pkg/errors"
+)
+
+const (
+	CapUnsafe   = "unsafe"
+	CapExec     = "exec"
+	CapNetwork  = "network"
+	CapFSWrite  = "fs-write"
+	CapSyscall  = "syscall"
+	CapReflect  = "reflect"
+	CapLinkname = "linkname"
+	CapCgo      = "cgo"
+	CapAssembly = "assembly"
+	CapInit     = "init"
+)
+
+// Every capability in the order that they're reported.
+var Capabilities = []string{
+	CapUnsafe,
+	CapExec,
+	CapNetwork,
+	CapFSWrite,
+	CapSyscall,
+	CapReflect,
+	CapLinkname,
+	CapCgo,
+	CapAssembly,
+	CapInit,
+}
+
+var capabilityImports = map[string]string{
+	"unsafe":                     CapUnsafe,
+	"os/exec":                    CapExec,
+	"syscall":                    CapSyscall,
+	"golang.org/x/sys/unix":      CapSyscall,
+	"golang.org/x/sys/windows":   CapSyscall,
+	"golang.org/x/sys/plan9":     CapSyscall,
+	"reflect":                    CapReflect,
+	"C":                          CapCgo,
+	"net":                        CapNetwork,
+	"net/http":                   CapNetwork,
+	"net/http/httptest":          CapNetwork,
+	"net/http/httputil":          CapNetwork,
+	"net/rpc":                    CapNetwork,
+	"net/rpc/jsonrpc":            CapNetwork,
+	"net/smtp":                   CapNetwork,
+	"crypto/tls":                 CapNetwork,
+	"golang.org/x/net/http2":     CapNetwork,
+	"golang.org/x/net/websocket": CapNetwork,
+}
+
+// Functions that modify the filesystem.
+var fsWriteFuncs = map[string][]string{
+	"os": {
+		"Chmod", "Chown", "Chtimes", "Create", "CreateTemp", "Lchown", "Link", "Mkdir", "MkdirAll",
+		"MkdirTemp", "OpenFile", "Remove", "RemoveAll", "Rename", "Symlink", "Truncate", "WriteFile",
+	},
+	"io/ioutil": {"TempDir", "TempFile", "WriteFile"},
+}
+
+type CapabilityUse struct {
+	Capability string
+	Package    string // import path of the package
+	Position   string // file:line relative to the module root
+	Detail     string // e.g. the imported package or the calls in init()
+}
+
+type ModuleCapabilities struct {
+	Name    string
+	Version string
+	Uses    []*CapabilityUse
+	Errors  []string `json:",omitempty"` // packages that couldn't be loaded
+}
+
+// Number of uses of each capability.
+func (m *ModuleCapabilities) Count() map[string]int {
+	count := map[string]int{}
+	for _, use := range m.Uses {
+		count[use.Capability]++
+	}
+	return count
+}
+
+// Report the use of sensitive APIs in the packages of every verified
+// module in GOPATH.  Modules that haven't been extracted are skipped.
+func (s *SumFile) Capabilities() ([]*ModuleCapabilities, error) {
+	result := []*ModuleCapabilities{}
+
+	for _, src := range s.Sources {
+		exists, err := iofs.Exists(src.DirPath())
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			s.log.Warnf("%s: not extracted, skipping", src)
+			continue
+		}
+
+		err = src.Verify(src.DirPath(), DirMode)
+		if err != nil {
+			return nil, err
+		}
+
+		caps, err := src.dirCapabilities(src.DirPath())
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, caps)
+	}
+
+	return result, nil
+}
+
+// Capabilities used by the packages in dir, which holds the verified content
+// of the module.  The packages are listed with `go list` without network
+// access, and the source files are parsed with go/parser.  Files that are
+// excluded by build constraints are included so that the result doesn't
+// depend on the platform that it's generated on.  Test files aren't
+// included.  A file that can't be parsed is an error since its capabilities
+// would otherwise go unnoticed.
+func (s *Source) dirCapabilities(dir string) (*ModuleCapabilities, error) {
+	caps := &ModuleCapabilities{Name: s.Name, Version: s.Version, Uses: []*CapabilityUse{}}
+
+	gomod, err := iofs.Exists(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Modules without a go.mod file are listed in GOPATH mode.
+	env := []string{
+		fmt.Sprintf("GOPATH=%s", s.GoPath),
+		"GOWORK=off",
+		"GOTOOLCHAIN=local",
+		"CGO_ENABLED=1",
+	}
+	if !gomod {
+		env = append(env, "GO111MODULE=off")
+	}
+
+	pkgs, err := gox.New(&gox.Options{Dir: dir, Env: env}).List("./...")
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	for _, pkg := range pkgs {
+		if pkg.Error != nil {
+			caps.Errors = append(caps.Errors, fmt.Sprintf("%s: %s", pkg.ImportPath, pkg.Error.Err))
+			s.log.Debugf("%s: %s: %s", s, pkg.ImportPath, pkg.Error.Err)
+		}
+		if pkg.Dir == "" {
+			continue
+		}
+
+		importPath := pkg.ImportPath
+		if !gomod {
+			rel, err := filepath.Rel(dir, pkg.Dir)
+			if err != nil {
+				return nil, err
+			}
+			importPath = filepath.ToSlash(filepath.Join(s.Name, rel))
+		}
+
+		files := append(append(append([]string{}, pkg.GoFiles...), pkg.CgoFiles...), pkg.IgnoredGoFiles...)
+		for _, name := range seq.Uniq(files) {
+			if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+				continue
+			}
+
+			path := filepath.Join(pkg.Dir, name)
+			file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+			if err != nil {
+				return nil, errors.Wrap(err, s.String())
+			}
+
+			uses, err := fileCapabilities(fset, file, dir)
+			if err != nil {
+				return nil, err
+			}
+			for _, use := range uses {
+				use.Package = importPath
+			}
+			caps.Uses = append(caps.Uses, uses...)
+		}
+
+		for _, name := range pkg.SFiles {
+			rel, err := filepath.Rel(dir, filepath.Join(pkg.Dir, name))
+			if err != nil {
+				return nil, err
+			}
+			caps.Uses = append(caps.Uses, &CapabilityUse{
+				Capability: CapAssembly,
+				Package:    importPath,
+				Position:   filepath.ToSlash(rel),
+			})
+		}
+	}
+
+	order := map[string]int{}
+	for i, capability := range Capabilities {
+		order[capability] = i
+	}
+	sort.SliceStable(caps.Uses, func(i int, j int) bool {
+		return order[caps.Uses[i].Capability] < order[caps.Uses[j].Capability]
+	})
+	return caps, nil
+}
+
+func fileCapabilities(fset *token.FileSet, file *ast.File, root string) ([]*CapabilityUse, error) {
+	uses := []*CapabilityUse{}
+
+	position := func(pos token.Pos) (string, error) {
+		p := fset.Position(pos)
+		rel, err := filepath.Rel(root, p.Filename)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s:%d", filepath.ToSlash(rel), p.Line), nil
+	}
+
+	fsWrite := map[string][]string{}
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		if funcs, ok := fsWriteFuncs[path]; ok {
+			name := filepath.Base(path)
+			if spec.Name != nil {
+				name = spec.Name.Name
+			}
+			fsWrite[name] = funcs
+		}
+
+		capability, ok := capabilityImports[path]
+		if !ok {
+			continue
+		}
+
+		pos, err := position(spec.Pos())
+		if err != nil {
+			return nil, err
+		}
+		uses = append(uses, &CapabilityUse{Capability: capability, Position: pos, Detail: path})
+	}
+
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			if !strings.HasPrefix(comment.Text, "//go:linkname ") {
+				continue
+			}
+
+			pos, err := position(comment.Pos())
+			if err != nil {
+				return nil, err
+			}
+			uses = append(uses, &CapabilityUse{
+				Capability: CapLinkname,
+				Position:   pos,
+				Detail:     strings.TrimPrefix(comment.Text, "//go:linkname "),
+			})
+		}
+	}
+
+	var inspectErr error
+	ast.Inspect(file, func(node ast.Node) bool {
+		sel, ok := node.(*ast.SelectorExpr)
+		if !ok || inspectErr != nil {
+			return inspectErr == nil
+		}
+
+		x, ok := sel.X.(*ast.Ident)
+		if !ok || x.Obj != nil || !seq.Contains(fsWrite[x.Name], sel.Sel.Name) {
+			return true
+		}
+
+		pos, err := position(sel.Pos())
+		if err != nil {
+			inspectErr = err
+			return false
+		}
+		uses = append(uses, &CapabilityUse{
+			Capability: CapFSWrite,
+			Position:   pos,
+			Detail:     fmt.Sprintf("%s.%s", x.Name, sel.Sel.Name),
+		})
+		return true
+	})
+	if inspectErr != nil {
+		return nil, inspectErr
+	}
+
+	for _, decl := range file.Decls {
+		fun, ok := decl.(*ast.FuncDecl)
+		if !ok || fun.Name.Name != "init" || fun.Recv != nil || fun.Body == nil {
+			continue
+		}
+
+		calls := initCalls(fun.Body)
+		if len(calls) == 0 {
+			continue
+		}
+
+		pos, err := position(fun.Pos())
+		if err != nil {
+			return nil, err
+		}
+		uses = append(uses, &CapabilityUse{
+			Capability: CapInit,
+			Position:   pos,
+			Detail:     strings.Join(calls, ", "),
+		})
+	}
+
+	return uses, nil
+}
+
+// Functions called by an init() function.  An init() function that only
+// assigns values without calling anything is considered to be free of side
+// effects.  Calls to builtins and conversions can't be told apart from other
+// calls without type information, so the most common builtins are ignored.
+func initCalls(body *ast.BlockStmt) []string {
+	builtins := []string{"append", "cap", "copy", "delete", "len", "make", "new", "panic"}
+	calls := []string{}
+
+	ast.Inspect(body, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+
+		name := ""
+		switch fun := call.Fun.(type) {
+		case *ast.Ident:
+			name = fun.Name
+		case *ast.SelectorExpr:
+			if x, ok := fun.X.(*ast.Ident); ok {
+				name = fmt.Sprintf("%s.%s", x.Name, fun.Sel.Name)
+			} else {
+				name = fmt.Sprintf("(...).%s", fun.Sel.Name)
+			}
+		case *ast.FuncLit:
+			name = "func literal"
+		default:
+			return true
+		}
+
+		if !seq.Contains(builtins, name) {
+			calls = append(calls, name)
+		}
+		return true
+	})
+
+	return seq.Uniq(calls)
+}
@@ -0,0 +1,157 @@
+package mod
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/illikainen/gofer/src/config"
+
+	"github.com/illikainen/go-cryptor/src/blob"
+	"github.com/illikainen/go-utils/src/errorx"
+	"github.com/illikainen/go-utils/src/fn"
+	"github.com/illikainen/go-utils/src/seq"
+	"github.com/pkg/errors"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+type CapabilityChange struct {
+	Name   string
+	Old    string // empty if the module is new
+	New    string
+	Gained []string // capabilities that the old version doesn't have
+	Denied []string // gained capabilities that aren't allowed by the policy
+}
+
+func (c *CapabilityChange) String() string {
+	old := fn.Ternary(c.Old != "", c.Old, "(none)")
+	msg := fmt.Sprintf("%s: %s => %s: gained %s", c.Name, old, c.New, strings.Join(c.Gained, ", "))
+	if len(c.Denied) > 0 {
+		msg += fmt.Sprintf(" (not allowed: %s)", strings.Join(c.Denied, ", "))
+	}
+	return msg
+}
+
+// Compare the capabilities of every module version in the go.sum file(s)
+// with the highest version of the same module in old, which may be nil.
+// Modules that aren't in old are compared against an empty set, so every
+// capability that they use must be allowed by the policy.  Only modules that
+// gain a capability are returned.
+func (s *SumFile) CapabilityDiff(old *SumFile, policy *config.CapabilityPolicy,
+	keyring *blob.Keyring) ([]*CapabilityChange, error) {
+	err := validateCapabilityPolicy(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []*CapabilityChange{}
+	seen := []string{}
+
+	for _, src := range s.Sources {
+		if seq.Contains(seen, src.String()) {
+			continue
+		}
+		seen = append(seen, src.String())
+
+		prev := []*Source{}
+		if old != nil {
+			prev = seq.FilterBy(old.Sources, func(o *Source, _ int) bool {
+				return o.Name == src.Name
+			})
+		}
+		if seq.ContainsBy(prev, func(o *Source) bool { return o.Version == src.Version }) {
+			continue
+		}
+
+		oldCaps := []string{}
+		change := &CapabilityChange{Name: src.Name, New: src.Version}
+		if len(prev) > 0 {
+			oldSrc := seq.MaxBy(prev, func(a *Source, b *Source) bool {
+				return semver.Compare(a.Version, b.Version) > 0
+			})
+			change.Old = oldSrc.Version
+
+			oldCaps, err = oldSrc.capabilitySet(keyring)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		newCaps, err := src.capabilitySet(keyring)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, capability := range newCaps {
+			if seq.Contains(oldCaps, capability) {
+				continue
+			}
+
+			change.Gained = append(change.Gained, capability)
+			if !capabilityAllowed(policy, src.Name, capability) {
+				change.Denied = append(change.Denied, capability)
+			}
+		}
+
+		if len(change.Gained) > 0 {
+			changes = append(changes, change)
+		}
+	}
+
+	return changes, nil
+}
+
+func validateCapabilityPolicy(policy *config.CapabilityPolicy) error {
+	for _, capability := range policy.Allow {
+		if !seq.Contains(Capabilities, capability) {
+			return errors.Errorf("invalid capability: %s", capability)
+		}
+	}
+
+	for name, caps := range policy.Modules {
+		for _, capability := range caps {
+			if !seq.Contains(Capabilities, capability) {
+				return errors.Errorf("%s: invalid capability: %s", name, capability)
+			}
+		}
+	}
+	return nil
+}
+
+func capabilityAllowed(policy *config.CapabilityPolicy, name string, capability string) bool {
+	if seq.Contains(policy.Allow, capability) {
+		return true
+	}
+
+	for prefix, caps := range policy.Modules {
+		if module.MatchPrefixPatterns(prefix, name) && seq.Contains(caps, capability) {
+			return true
+		}
+	}
+	return false
+}
+
+// Capabilities used by the module.  A module without any verified source
+// is an error since the capabilities that it gains can't be known.
+func (s *Source) capabilitySet(keyring *blob.Keyring) (caps []string, err error) {
+	dir, cleanup, err := s.VerifiedDir(keyring)
+	if err != nil {
+		return nil, err
+	}
+	if dir == "" {
+		return nil, errors.Errorf("%s: no verified source to check the capabilities of", s)
+	}
+	defer errorx.Defer(cleanup, &err)
+
+	mc, err := s.dirCapabilities(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, use := range mc.Uses {
+		if !seq.Contains(caps, use.Capability) {
+			caps = append(caps, use.Capability)
+		}
+	}
+	return caps, nil
+}
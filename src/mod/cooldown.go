@@ -0,0 +1,127 @@
+package mod
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/illikainen/gofer/src/config"
+
+	"github.com/illikainen/go-utils/src/iofs"
+	"github.com/illikainen/go-utils/src/seq"
+	"github.com/pkg/errors"
+)
+
+// Cooldown refuses module versions that were published too recently.
+// Malicious versions are often discovered and retracted within days, so
+// waiting before a version is trusted narrows the window for such attacks.
+type Cooldown struct {
+	MinAge time.Duration
+	Allow  []string // module paths or path@version
+	Now    time.Time
+}
+
+// Parse the cooldown policy.  Nil is returned if the policy is disabled.
+func NewCooldown(policy *config.CooldownPolicy) (*Cooldown, error) {
+	if policy.MinAge == "" {
+		return nil, nil
+	}
+
+	age, err := parseAge(policy.MinAge)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cooldown{MinAge: age, Allow: policy.Allow, Now: time.Now().UTC()}, nil
+}
+
+// Check the publish time in the verified .info file of every module version
+// with code in the go.sum file(s).  Versions without a .info file are
+// refused since their age is unknown.
+func (c *Cooldown) Check(s *SumFile) error {
+	refused := 0
+
+	for _, src := range s.Sources {
+		if seq.Contains(c.Allow, src.Name) || seq.Contains(c.Allow, src.Name+"@"+src.Version) {
+			s.log.Infof("%s: allowed regardless of its age", src)
+			continue
+		}
+
+		info, err := s.info(src.Name, src.Version)
+		if err != nil {
+			s.log.Errorf("%s: unknown publish time: %s", src, err)
+			refused++
+			continue
+		}
+
+		published, err := time.Parse(time.RFC3339, info.Time)
+		if err != nil {
+			return err
+		}
+
+		eligible := published.Add(c.MinAge)
+		if c.Now.Before(eligible) {
+			s.log.Errorf("%s: published %s, eligible after %s", src, published.Format(time.RFC3339),
+				eligible.Format(time.RFC3339))
+			refused++
+			continue
+		}
+		s.log.Debugf("%s: published %s", src, published.Format(time.RFC3339))
+	}
+
+	if refused > 0 {
+		return errors.Errorf("%d module version(s) refused by the cooldown policy", refused)
+	}
+	return nil
+}
+
+// Verified .info for a module version.  The .info files that have already
+// been parsed through the .mod files are preferred over GOPATH.
+func (s *SumFile) info(name string, version string) (*Info, error) {
+	for _, m := range s.ModFiles {
+		for _, i := range m.InfoFiles {
+			if i.Name == name && i.Version == version && i.Info != nil {
+				return i.Info, nil
+			}
+		}
+	}
+
+	i := s.newInfoFile(name, version)
+	exists, err := iofs.Exists(i.InfoPath())
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.Errorf("%s: not available", i.InfoPath())
+	}
+
+	err = i.Verify(i.InfoPath())
+	if err != nil {
+		return nil, err
+	}
+
+	if i.Info.Version != version {
+		return nil, errors.Errorf("%s: version mismatch: %s", i.InfoPath(), i.Info.Version)
+	}
+	return i.Info, nil
+}
+
+// Durations with a day (d) or week (w) suffix in addition to the units
+// supported by time.ParseDuration().
+func parseAge(age string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if strings.HasSuffix(age, suffix) {
+			value, err := strconv.ParseUint(strings.TrimSuffix(age, suffix), 10, 16)
+			if err != nil {
+				return 0, errors.Errorf("invalid age: %s", age)
+			}
+			return time.Duration(value) * unit, nil
+		}
+	}
+
+	duration, err := time.ParseDuration(age)
+	if err != nil || duration < 0 {
+		return 0, errors.Errorf("invalid age: %s", age)
+	}
+	return duration, nil
+}
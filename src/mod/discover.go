@@ -0,0 +1,156 @@
+package mod
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/illikainen/go-utils/src/fn"
+	"github.com/illikainen/go-utils/src/iofs"
+	"github.com/illikainen/go-utils/src/logging"
+	"github.com/pkg/errors"
+)
+
+type ResolveOptions struct {
+	SumFiles  []string // explicitly specified go.sum files
+	Workspace string   // directory with a go.work file
+	Auto      bool     // discover go.sum files from the current directory
+	Log       logging.Logger
+}
+
+// Resolve the set of go.sum files that a mod command operates on.
+func ResolveSumFiles(opts *ResolveOptions) (*Workspace, error) {
+	log := fn.Ternary(opts.Log != nil, opts.Log, logging.DiscardLogger())
+	ws := &Workspace{}
+	ws.merge(&Workspace{SumFiles: opts.SumFiles})
+
+	if opts.Workspace != "" {
+		work, err := ReadWorkspace(opts.Workspace)
+		if err != nil {
+			return nil, err
+		}
+		ws.merge(work)
+	}
+
+	if opts.Auto {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+
+		discovered, err := Discover(cwd)
+		if err != nil {
+			return nil, err
+		}
+		ws.merge(discovered)
+	}
+
+	if len(ws.SumFiles) == 0 {
+		return nil, errors.Errorf("no go.sum file(s) specified")
+	}
+
+	if opts.Workspace != "" || opts.Auto {
+		log.Info("resolved go.sum file(s):")
+		for _, file := range ws.SumFiles {
+			log.Infof("    %s", file)
+		}
+	}
+
+	return ws, nil
+}
+
+// Discover the go.sum files that are relevant for dir: the go.sum for the
+// module in dir, the go.sum for every other module in the same repository
+// and the checksum files for the go.work workspace that dir belongs to.
+// Like the Go command, vendor and testdata directories as well as
+// directories that start with a "." or an "_" are ignored.
+func Discover(dir string) (*Workspace, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	modRoot, err := findUp(dir, "go.mod")
+	if err != nil {
+		return nil, err
+	}
+
+	repoRoot, err := findUp(dir, ".git")
+	if err != nil {
+		return nil, err
+	}
+
+	root := repoRoot
+	if root == "" {
+		root = modRoot
+	}
+	if root == "" {
+		return nil, errors.Errorf("%s is not in a module", dir)
+	}
+
+	ws := &Workspace{Root: root}
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() {
+			return nil
+		}
+
+		name := d.Name()
+		if path != root && (name == "vendor" || name == "testdata" ||
+			strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+			return filepath.SkipDir
+		}
+
+		exists, err := iofs.Exists(filepath.Join(path, "go.mod"))
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ws.addSumFile(filepath.Join(path, "go.sum"))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if os.Getenv("GOWORK") != "off" {
+		workRoot, err := findUp(dir, "go.work")
+		if err != nil {
+			return nil, err
+		}
+
+		if workRoot != "" {
+			work, err := ReadWorkspace(workRoot)
+			if err != nil {
+				return nil, err
+			}
+			ws.merge(work)
+		}
+	}
+
+	return ws, nil
+}
+
+// Find the closest directory, starting with dir, that contains name.
+func findUp(dir string, name string) (string, error) {
+	for {
+		exists, err := iofs.Exists(filepath.Join(dir, name))
+		if err != nil {
+			return "", err
+		}
+		if exists {
+			return dir, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
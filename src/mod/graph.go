@@ -0,0 +1,361 @@
+package mod
+
+import (
+	"path/filepath"
+	"sort"
+
+	"github.com/illikainen/go-cryptor/src/blob"
+	"github.com/illikainen/go-utils/src/iofs"
+	"github.com/illikainen/go-utils/src/seq"
+	"github.com/pkg/errors"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+const (
+	SumSelected = "selected" // in the build list
+	SumGraph    = "graph"    // only needed to compute the build list
+	SumUnneeded = "unneeded" // not reachable from the main module(s)
+	SumLocal    = "local"    // replaced by a local directory
+	SumExcluded = "excluded" // excluded by the main module(s)
+)
+
+type GraphOptions struct {
+	ModFiles []string      // go.mod for the main module(s)
+	Keyring  *blob.Keyring // optional, used for .mod files that only exist as signed blobs
+}
+
+// Graph is the module requirement graph as seen by minimal version
+// selection.  It's built entirely from go.mod files that have been verified
+// against go.sum, so no network access or Go command is involved.
+//
+// Graph pruning (go >= 1.17) isn't implemented; every requirement is
+// followed, which yields a superset of the modules that Go would load.
+type Graph struct {
+	Main      []module.Version
+	Edges     map[module.Version][]module.Version
+	BuildList []module.Version
+	Missing   []module.Version // requirements without a .mod file to verify
+	Unlisted  []module.Version // requirements without a /go.mod line in go.sum
+	Excluded  []module.Version
+	replaced  map[module.Version]module.Version
+	local     []module.Version
+	goVersion map[module.Version]string
+}
+
+// Returned by requirements() for modules without a .mod file to verify.
+// Other errors, e.g. a checksum mismatch, aren't a missing module.
+var errModNotAvailable = errors.New("not available")
+
+type SumEntry struct {
+	Mod    module.Version
+	GoMod  bool // the /go.mod checksum rather than the module zip
+	Status string
+}
+
+func (s *SumFile) Graph(opts *GraphOptions) (*Graph, error) {
+	g := &Graph{
+		Edges:     map[module.Version][]module.Version{},
+		replaced:  map[module.Version]module.Version{},
+		goVersion: map[module.Version]string{},
+	}
+
+	replaces := map[module.Version]*modfile.Replace{}
+	queue := []module.Version{}
+	for _, file := range opts.ModFiles {
+		mod, err := ParseMod(file)
+		if err != nil {
+			return nil, err
+		}
+
+		main := module.Version{Path: mod.Module.Mod.Path}
+		g.Main = append(g.Main, main)
+		if mod.Go != nil {
+			g.goVersion[main] = mod.Go.Version
+		}
+
+		for _, exclude := range mod.Exclude {
+			g.Excluded = append(g.Excluded, exclude.Mod)
+		}
+
+		for _, replace := range mod.Replace {
+			if isLocalReplace(replace) {
+				replace.New.Path = localPath(filepath.Dir(file), replace.New.Path)
+			}
+			replaces[replace.Old] = replace
+		}
+
+		for _, req := range mod.Require {
+			g.Edges[main] = append(g.Edges[main], req.Mod)
+		}
+		queue = append(queue, g.Edges[main]...)
+	}
+
+	visited := []module.Version{}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+
+		if seq.Contains(visited, node) || g.isMain(node.Path) || seq.Contains(g.Excluded, node) {
+			continue
+		}
+		visited = append(visited, node)
+
+		reqs, err := s.requirements(g, node, replaces, opts.Keyring)
+		if errors.Is(err, errModNotAvailable) {
+			s.log.Debugf("%s: %s", node, err)
+			g.Missing = append(g.Missing, node)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		reqs = seq.FilterBy(reqs, func(req module.Version, _ int) bool {
+			return !seq.Contains(g.Excluded, req)
+		})
+		g.Edges[node] = reqs
+		queue = append(queue, reqs...)
+	}
+
+	selected := map[string]string{}
+	for _, node := range visited {
+		if semver.Compare(node.Version, selected[node.Path]) > 0 {
+			selected[node.Path] = node.Version
+		}
+	}
+
+	g.BuildList = append([]module.Version{}, g.Main...)
+	for path, version := range selected {
+		g.BuildList = append(g.BuildList, module.Version{Path: path, Version: version})
+	}
+	sortVersions(g.BuildList[len(g.Main):])
+	sortVersions(g.Missing)
+	sortVersions(g.Unlisted)
+
+	return g, nil
+}
+
+func (s *SumFile) requirements(g *Graph, node module.Version, replaces map[module.Version]*modfile.Replace,
+	keyring *blob.Keyring) ([]module.Version, error) {
+	target := node
+	replace, ok := replaces[node]
+	if !ok {
+		replace, ok = replaces[module.Version{Path: node.Path}]
+	}
+	if ok {
+		if isLocalReplace(replace) {
+			g.local = append(g.local, node)
+			mod, err := ParseMod(filepath.Join(replace.New.Path, "go.mod"))
+			if err != nil {
+				return nil, err
+			}
+
+			if mod.Go != nil {
+				g.goVersion[node] = mod.Go.Version
+			}
+
+			reqs := []module.Version{}
+			for _, req := range mod.Require {
+				reqs = append(reqs, req.Mod)
+			}
+			return reqs, nil
+		}
+
+		target = replace.New
+		g.replaced[node] = target
+	}
+
+	m, ok := seq.FindBy(s.ModFiles, func(m *ModFile) bool {
+		return m.Name == target.Path && m.Version == target.Version
+	})
+	if !ok {
+		g.Unlisted = append(g.Unlisted, target)
+		return nil, errors.Wrapf(errModNotAvailable, "%s: missing in go.sum", target)
+	}
+
+	exists, err := iofs.Exists(m.ModPath())
+	if err != nil {
+		return nil, err
+	}
+
+	signed := false
+	if !exists && keyring != nil && len(keyring.Public) > 0 {
+		signed, err = iofs.Exists(m.SigPath())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case exists:
+		err = m.Verify(m.ModPath())
+	case signed:
+		err = m.VerifySigned(keyring)
+	default:
+		err = errors.Wrap(errModNotAvailable, m.String())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	g.goVersion[node] = m.GoVersion
+	return m.Require, nil
+}
+
+// Report whether the /go.mod line of mod must be in go.sum.  Modules at go
+// >= 1.17 have pruned module graphs, so the requirements of their
+// requirements are only needed if they're required by a main module, a
+// direct dependency of a main module or a module with an unpruned graph.
+func (g *Graph) Needed(mod module.Version) bool {
+	for node, reqs := range g.Edges {
+		if !seq.Contains(reqs, mod) {
+			continue
+		}
+
+		if g.isMain(node.Path) || !pruned(g.goVersion[node]) {
+			return true
+		}
+
+		direct := seq.ContainsBy(g.Main, func(main module.Version) bool {
+			return seq.Contains(g.Edges[main], node)
+		})
+		if direct {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Graph) isMain(path string) bool {
+	return seq.ContainsBy(g.Main, func(main module.Version) bool {
+		return main.Path == path
+	})
+}
+
+// Classify every go.sum entry based on whether it's needed by the build.
+// Module zips are only needed for the build list, whereas .mod files are
+// needed for every module version in the graph.
+func (g *Graph) Classify(s *SumFile) []*SumEntry {
+	selected := []module.Version{}
+	for _, mod := range g.BuildList {
+		selected = append(selected, g.resolve(mod))
+	}
+
+	inGraph := []module.Version{}
+	for node := range g.Edges {
+		inGraph = append(inGraph, g.resolve(node))
+	}
+
+	classify := func(mod module.Version, gomod bool) *SumEntry {
+		entry := &SumEntry{Mod: mod, GoMod: gomod, Status: SumUnneeded}
+		switch {
+		case seq.Contains(g.Excluded, mod):
+			entry.Status = SumExcluded
+		case seq.Contains(selected, mod):
+			entry.Status = SumSelected
+		case gomod && seq.Contains(inGraph, mod):
+			entry.Status = SumGraph
+		case seq.Contains(g.local, mod):
+			entry.Status = SumLocal
+		}
+		return entry
+	}
+
+	entries := []*SumEntry{}
+	for _, src := range s.Sources {
+		entries = append(entries, classify(module.Version{Path: src.Name, Version: src.Version}, false))
+	}
+	for _, m := range s.ModFiles {
+		entries = append(entries, classify(module.Version{Path: m.Name, Version: m.Version}, true))
+	}
+
+	sort.SliceStable(entries, func(i int, j int) bool {
+		if entries[i].Mod == entries[j].Mod {
+			return !entries[i].GoMod && entries[j].GoMod
+		}
+		return lessVersion(entries[i].Mod, entries[j].Mod)
+	})
+	return entries
+}
+
+// Find the shortest requirement path from a main module to target.  If the
+// version of target is empty, any version of the module matches.
+func (g *Graph) Why(target module.Version) []module.Version {
+	prev := map[module.Version]module.Version{}
+	queue := append([]module.Version{}, g.Main...)
+	visited := append([]module.Version{}, g.Main...)
+
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+
+		if node.Path == target.Path && (target.Version == "" || node.Version == target.Version) {
+			path := []module.Version{node}
+			for !seq.Contains(g.Main, node) {
+				node = prev[node]
+				path = append([]module.Version{node}, path...)
+			}
+			return path
+		}
+
+		for _, req := range g.Edges[node] {
+			if !seq.Contains(visited, req) {
+				visited = append(visited, req)
+				prev[req] = node
+				queue = append(queue, req)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Edges in the same format as `go mod graph`.
+func (g *Graph) Lines() [][2]string {
+	nodes := []module.Version{}
+	for node := range g.Edges {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i int, j int) bool {
+		if g.isMain(nodes[i].Path) != g.isMain(nodes[j].Path) {
+			return g.isMain(nodes[i].Path)
+		}
+		return lessVersion(nodes[i], nodes[j])
+	})
+
+	lines := [][2]string{}
+	for _, node := range nodes {
+		reqs := append([]module.Version{}, g.Edges[node]...)
+		sortVersions(reqs)
+		for _, req := range reqs {
+			lines = append(lines, [2]string{node.String(), req.String()})
+		}
+	}
+	return lines
+}
+
+func (g *Graph) resolve(mod module.Version) module.Version {
+	if target, ok := g.replaced[mod]; ok {
+		return target
+	}
+	return mod
+}
+
+func pruned(goVersion string) bool {
+	return goVersion != "" && semver.Compare("v"+goVersion, "v1.17") >= 0
+}
+
+func lessVersion(a module.Version, b module.Version) bool {
+	if a.Path != b.Path {
+		return a.Path < b.Path
+	}
+	return semver.Compare(a.Version, b.Version) < 0
+}
+
+func sortVersions(mods []module.Version) {
+	sort.Slice(mods, func(i int, j int) bool {
+		return lessVersion(mods[i], mods[j])
+	})
+}
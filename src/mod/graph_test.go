@@ -0,0 +1,44 @@
+package mod
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/mod/module"
+)
+
+func TestGraphMissing(t *testing.T) {
+	m := newTestModule(t, "example.com/m", "v1.0.0", "")
+	main := filepath.Join(t.TempDir(), "go.mod")
+	err := os.WriteFile(main, []byte("module example.com/main\n\nrequire example.com/m v1.0.0\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sum := newTestSumFile(t, t.TempDir(), m)
+	g, err := sum.Graph(&GraphOptions{ModFiles: []string{main}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Missing) != 1 || g.Missing[0] != (module.Version{Path: m.Name, Version: m.Version}) {
+		t.Fatalf("unexpected missing modules: %v", g.Missing)
+	}
+
+	// A .mod file that doesn't match go.sum isn't missing.
+	modPath := sum.ModFiles[0].ModPath()
+	err = os.MkdirAll(filepath.Dir(modPath), 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(modPath, []byte("module example.com/m\n\nrequire example.com/evil v1.0.0\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = sum.Graph(&GraphOptions{ModFiles: []string{main}})
+	if err == nil || !strings.Contains(err.Error(), "bad checksum") {
+		t.Fatalf("expected a checksum mismatch, got %v", err)
+	}
+}
@@ -0,0 +1,237 @@
+package mod
+
+import (
+	"bufio"
+	"bytes"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/illikainen/go-cryptor/src/blob"
+	"github.com/illikainen/go-utils/src/iofs"
+	"github.com/illikainen/go-utils/src/seq"
+	"github.com/pkg/errors"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+type IndexResult struct {
+	Lists   []string
+	Latests []string
+}
+
+// Go resolves versions through the <module>/@v/list and <module>/@latest
+// files when GOPROXY is set to file://$GOPATH/pkg/mod/cache/download.  The
+// Go command doesn't create them when it downloads modules, so they're
+// generated here from the verified .mod and .info files in GOPATH.
+//
+// Versions that are already listed are kept as long as their .mod file
+// in GOPATH is identical to a signed one, so that indexing one go.sum
+// doesn't hide modules that were retrieved for another.
+func (s *SumFile) WriteIndex(keyring *blob.Keyring) (ir *IndexResult, err error) {
+	versions := map[string][]string{}
+	for _, m := range s.ModFiles {
+		exists, err := iofs.Exists(m.ModPath())
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			s.log.Debugf("%s: not available", m)
+			continue
+		}
+
+		err = m.Verify(m.ModPath())
+		if err != nil {
+			return nil, err
+		}
+
+		if !seq.Contains(versions[m.Name], m.Version) {
+			versions[m.Name] = append(versions[m.Name], m.Version)
+		}
+	}
+
+	names := []string{}
+	for name := range versions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ir = &IndexResult{}
+	for _, name := range names {
+		dir := downloadDir(s.goPath, name)
+		listPath := filepath.Join(dir, "@v", "list")
+
+		listed, err := readList(listPath)
+		if err != nil {
+			return nil, err
+		}
+
+		all := append([]string{}, versions[name]...)
+		for _, version := range listed {
+			if seq.Contains(all, version) {
+				continue
+			}
+
+			signed, err := s.isSigned(s.newModFile(name, version), keyring)
+			if err != nil {
+				return nil, err
+			}
+			if !signed {
+				s.log.Debugf("%s@%s: unlisted since it isn't signed", name, version)
+				continue
+			}
+			all = append(all, version)
+		}
+		semver.Sort(all)
+
+		// Pseudo-versions aren't part of the list according to the
+		// GOPROXY protocol, but they may still be resolved as @latest.
+		list := seq.FilterBy(all, func(version string, _ int) bool {
+			return !module.IsPseudoVersion(version)
+		})
+
+		data := ""
+		for _, version := range list {
+			data += version + "\n"
+		}
+
+		err = writeFile(listPath, []byte(data))
+		if err != nil {
+			return nil, err
+		}
+		s.log.Infof("%s: listed %d version(s)", name, len(list))
+		ir.Lists = append(ir.Lists, listPath)
+
+		latest, ok := latestVersion(all)
+		if !ok {
+			continue
+		}
+
+		info := s.newInfoFile(name, latest)
+
+		exists, err := iofs.Exists(info.InfoPath())
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			s.log.Debugf("%s: no info for %s", name, latest)
+			continue
+		}
+
+		err = info.Verify(info.InfoPath())
+		if err != nil {
+			return nil, err
+		}
+
+		if info.Info.Version != latest {
+			return nil, errors.Errorf("%s: invalid version: %s", info.InfoPath(), info.Info.Version)
+		}
+
+		infoData, err := iofs.ReadFile(info.InfoPath())
+		if err != nil {
+			return nil, err
+		}
+
+		latestPath := filepath.Join(dir, "@latest")
+		err = writeFile(latestPath, infoData)
+		if err != nil {
+			return nil, err
+		}
+		s.log.Infof("%s: latest is %s", name, latest)
+		ir.Latests = append(ir.Latests, latestPath)
+	}
+
+	return ir, nil
+}
+
+// Check whether the .mod file of a version that isn't in the go.sum
+// file(s) is in GOPATH and identical to the payload of its signed blob.
+// A .mod file that differs is logged and treated as unsigned.
+func (s *SumFile) isSigned(m *ModFile, keyring *blob.Keyring) (bool, error) {
+	for _, file := range []string{m.ModPath(), m.SigPath()} {
+		exists, err := iofs.Exists(file)
+		if err != nil || !exists {
+			return false, err
+		}
+	}
+
+	signed, err := readSigned(m.SigPath(), keyring)
+	if err != nil {
+		return false, err
+	}
+
+	data, err := iofs.ReadFile(m.ModPath())
+	if err != nil {
+		return false, err
+	}
+
+	// A stale file of an unrelated version shouldn't prevent the rest of
+	// the module from being indexed.
+	if !bytes.Equal(data, signed) {
+		s.log.Warnf("%s: not identical to %s", m.ModPath(), m.SigPath())
+		return false, nil
+	}
+	return true, nil
+}
+
+func readList(file string) ([]string, error) {
+	exists, err := iofs.Exists(file)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	data, err := iofs.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := []string{}
+	scan := bufio.NewScanner(bytes.NewReader(data))
+	for scan.Scan() {
+		line := strings.TrimSpace(scan.Text())
+		if line == "" {
+			continue
+		}
+
+		version, mod, err := validateVersion(line)
+		if err != nil {
+			return nil, err
+		}
+		if mod {
+			return nil, errors.Errorf("%s: invalid version: %s", file, line)
+		}
+
+		versions = append(versions, version)
+	}
+
+	return versions, scan.Err()
+}
+
+// Mimic the GOPROXY @latest query: the highest release is preferred over
+// the highest pre-release, which in turn is preferred over the highest
+// pseudo-version.
+func latestVersion(versions []string) (string, bool) {
+	var release, prerelease, pseudo string
+
+	for _, version := range versions {
+		switch {
+		case module.IsPseudoVersion(version):
+			if semver.Compare(version, pseudo) > 0 {
+				pseudo = version
+			}
+		case semver.Prerelease(version) != "":
+			if semver.Compare(version, prerelease) > 0 {
+				prerelease = version
+			}
+		default:
+			if semver.Compare(version, release) > 0 {
+				release = version
+			}
+		}
+	}
+
+	return seq.Coalesce(release, prerelease, pseudo)
+}
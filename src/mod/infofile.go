@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -28,7 +29,9 @@ type InfoFile struct {
 	Name     string // e.g. github.com/BurntSushi/toml
 	Version  string // e.g. v1.3.2
 	GoPath   string // e.g. $HOME/go
+	Info     *Info  // set once the file has been verified
 	sigPath  string // e.g. $HOME/.cache/gofer/mod
+	origins  map[string]string
 	log      logging.Logger
 	verified bool
 }
@@ -39,15 +42,19 @@ func (i *InfoFile) Verify(file string) error {
 		return err
 	}
 
+	return i.verifyData(file, data)
+}
+
+func (i *InfoFile) verifyData(name string, data []byte) error {
 	if !bytes.Equal(stringx.Sanitize(data), data) {
-		return errors.Errorf("invalid content in %s", file)
+		return errors.Errorf("invalid content in %s", name)
 	}
 
 	decoder := json.NewDecoder(bytes.NewReader(data))
 	decoder.DisallowUnknownFields()
 
 	var info Info
-	err = decoder.Decode(&info)
+	err := decoder.Decode(&info)
 	if err != nil {
 		return err
 	}
@@ -57,11 +64,42 @@ func (i *InfoFile) Verify(file string) error {
 		return err
 	}
 
+	// The origin URL of a module path without a mapping has still been
+	// checked against the allowed hosts by info.Verify().
+	err = info.verifyOrigin(i.Name, i.origins)
+	switch {
+	case errors.Is(err, errNoOrigin):
+		i.log.Warnf("%s: %s", name, err)
+	case err != nil:
+		return err
+	}
+
+	i.Info = &info
 	i.verified = true
-	i.log.Tracef("%s: successfully verified json", file)
+	i.log.Tracef("%s: successfully verified json", name)
 	return nil
 }
 
+// Verify the .info file in the signed blob at SigPath() without writing it
+// anywhere.
+func (i *InfoFile) VerifySigned(keyring *blob.Keyring) error {
+	data, err := readSigned(i.SigPath(), keyring)
+	if err != nil {
+		return err
+	}
+
+	return i.verifyData(i.SigPath(), data)
+}
+
+func (i *InfoFile) verifyStream(r io.Reader, _ *os.File) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	return i.verifyData(i.String(), data)
+}
+
 func (i *InfoFile) Sign(src string, dst string, keyring *blob.Keyring) (err error) {
 	if !i.verified {
 		return errors.Errorf("%s has not been verified", src)
@@ -99,96 +137,18 @@ func (i *InfoFile) Sign(src string, dst string, keyring *blob.Keyring) (err erro
 
 func (i *InfoFile) DownloadAndVerify(uri *url.URL, sigOutput string, goOutput string,
 	keyring *blob.Keyring) (signer cryptor.PublicKey, verified string, err error) {
-	tmp, tmpRm, err := iofs.MkdirTemp()
+	signer, err = fetchSigned(uri, sigOutput, goOutput, keyring, i.verifyStream, false)
 	if err != nil {
 		return nil, "", err
 	}
-	defer errorx.Defer(tmpRm, &err)
-
-	sigPathExists, err := iofs.Exists(sigOutput)
-	if err != nil {
-		return nil, "", err
-	}
-
-	if !sigPathExists {
-		tmpSigPath := filepath.Join(tmp, "tmp-sig")
-		tmpSig, err := os.OpenFile(tmpSigPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600) // #nosec G304
-		if err != nil {
-			return nil, "", err
-		}
-		defer errorx.Defer(tmpSig.Close, &err)
-
-		blobber, err := blob.Download(uri, tmpSig, &blob.Options{
-			Type:      metadata.Name(),
-			Keyring:   keyring,
-			Encrypted: false,
-		})
-		if err != nil {
-			return nil, "", err
-		}
-
-		tmpInfoPath := filepath.Join(tmp, "tmp-info")
-		err = iofs.Copy(tmpInfoPath, blobber)
-		if err != nil {
-			return nil, "", err
-		}
-
-		err = i.Verify(tmpInfoPath)
-		if err != nil {
-			return nil, "", err
-		}
-
-		err = iofs.MoveFile(tmpSigPath, sigOutput)
-		if err != nil {
-			return nil, "", err
-		}
-	}
-
-	sig, err := os.Open(sigOutput) // #nosec G304
-	if err != nil {
-		return nil, "", err
-	}
-	defer errorx.Defer(sig.Close, &err)
-
-	blobber, err := blob.NewReader(sig, &blob.Options{
-		Type:      metadata.Name(),
-		Keyring:   keyring,
-		Encrypted: false,
-	})
-	if err != nil {
-		return nil, "", err
-	}
-	i.log.Tracef("%s: signed by: %s", sigOutput, blobber.Signer)
-
-	tmpInfoPath := filepath.Join(tmp, "info")
-	err = iofs.Copy(tmpInfoPath, blobber)
-	if err != nil {
-		return nil, "", err
-	}
-
-	err = i.Verify(tmpInfoPath)
-	if err != nil {
-		return nil, "", err
-	}
-
-	infoPathExists, err := iofs.Exists(goOutput)
-	if err != nil {
-		return nil, "", err
-	}
-
-	if !infoPathExists {
-		err := iofs.MoveFile(tmpInfoPath, goOutput)
-		if err != nil {
-			return nil, "", err
-		}
-	}
+	i.log.Tracef("%s: signed by: %s", sigOutput, signer)
 
 	err = i.Verify(goOutput)
 	if err != nil {
 		return nil, "", err
 	}
 
-	return blobber.Signer, "json", nil
+	return signer, "json", nil
 }
 
 // Name of the signed .info file.
@@ -0,0 +1,166 @@
+package mod
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"github.com/illikainen/gofer/src/config"
+	"github.com/illikainen/gofer/src/license"
+
+	"github.com/illikainen/go-cryptor/src/blob"
+	"github.com/illikainen/go-utils/src/errorx"
+	"github.com/illikainen/go-utils/src/seq"
+	"github.com/pkg/errors"
+)
+
+// License texts larger than this are classified by their beginning.
+const maxLicenseSize = 1 << 20
+
+type LicenseFile struct {
+	Path     string   // relative to the module root
+	Licenses []string // SPDX identifiers or license.Unknown
+}
+
+type ModuleLicense struct {
+	Name     string
+	Version  string
+	Licenses []string // licenses of every file, license.Unknown if there's no license file
+	Files    []*LicenseFile
+}
+
+func (m *ModuleLicense) String() string {
+	return fmt.Sprintf("%s@%s: %s", m.Name, m.Version, strings.Join(m.Licenses, ", "))
+}
+
+// Classify the license files in every module version.  The verified
+// directory in GOPATH or the verified zip is used, and module versions
+// without either are skipped with a warning.  CheckLicenses() refuses
+// them.
+func (s *SumFile) Licenses(keyring *blob.Keyring) ([]*ModuleLicense, error) {
+	result := []*ModuleLicense{}
+	seen := []string{}
+
+	for _, src := range s.Sources {
+		if seq.Contains(seen, src.String()) {
+			continue
+		}
+		seen = append(seen, src.String())
+
+		fsys, err := src.VerifiedFS(keyring)
+		if err != nil {
+			return nil, err
+		}
+		if fsys == nil {
+			s.log.Warnf("%s: not available, skipping", src)
+			continue
+		}
+
+		ml := &ModuleLicense{Name: src.Name, Version: src.Version, Files: []*LicenseFile{}}
+		err = fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				if name != "." && entry.Name() == "testdata" {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if !entry.Type().IsRegular() || !license.IsLicenseFile(name) {
+				return nil
+			}
+
+			ids, err := classifyLicense(fsys, name)
+			if err != nil {
+				return err
+			}
+			ml.Files = append(ml.Files, &LicenseFile{Path: name, Licenses: ids})
+			ml.Licenses = append(ml.Licenses, ids...)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		ml.Licenses = seq.Uniq(ml.Licenses)
+		sort.Strings(ml.Licenses)
+		if len(ml.Licenses) == 0 {
+			ml.Licenses = []string{license.Unknown}
+		}
+		result = append(result, ml)
+	}
+
+	return result, nil
+}
+
+func classifyLicense(fsys fs.FS, name string) (ids []string, err error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer errorx.Defer(f.Close, &err)
+
+	data, err := io.ReadAll(io.LimitReader(f, maxLicenseSize))
+	if err != nil {
+		return nil, err
+	}
+
+	return license.Classify(data), nil
+}
+
+// Check the licenses against the policy.  Every denied module is logged
+// and an error is returned if there's at least one.  Module versions that
+// weren't classified, e.g. because their source isn't available, are
+// denied since their license is unknown rather than missing.
+func (s *SumFile) CheckLicenses(licenses []*ModuleLicense, policy *config.LicensePolicy) error {
+	known := append(license.IDs(), license.Unknown)
+	for _, id := range append(append([]string{}, policy.Allow...), policy.Deny...) {
+		if !seq.Contains(known, id) {
+			return errors.Errorf("invalid license: %s", id)
+		}
+	}
+
+	exempt := func(name string, version string) bool {
+		return seq.Contains(policy.Exempt, name) || seq.Contains(policy.Exempt, name+"@"+version)
+	}
+
+	denied := 0
+	seen := []string{}
+	for _, src := range s.Sources {
+		if seq.Contains(seen, src.String()) {
+			continue
+		}
+		seen = append(seen, src.String())
+
+		classified := seq.ContainsBy(licenses, func(ml *ModuleLicense) bool {
+			return ml.Name == src.Name && ml.Version == src.Version
+		})
+		if !classified && !exempt(src.Name, src.Version) {
+			s.log.Errorf("%s: no verified source to classify the license of", src)
+			denied++
+		}
+	}
+
+	for _, ml := range licenses {
+		if exempt(ml.Name, ml.Version) {
+			s.log.Debugf("%s: exempt from the license policy", ml)
+			continue
+		}
+
+		refused := seq.FilterBy(ml.Licenses, func(id string, _ int) bool {
+			return seq.Contains(policy.Deny, id) || (len(policy.Allow) > 0 && !seq.Contains(policy.Allow, id))
+		})
+		if len(refused) > 0 {
+			s.log.Errorf("%s@%s: license not allowed: %s", ml.Name, ml.Version, strings.Join(refused, ", "))
+			denied++
+		}
+	}
+
+	if denied > 0 {
+		return errors.Errorf("%d module(s) refused by the license policy", denied)
+	}
+	return nil
+}
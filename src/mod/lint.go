@@ -0,0 +1,107 @@
+package mod
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/illikainen/go-utils/src/seq"
+	"golang.org/x/mod/module"
+)
+
+const (
+	LintConflict    = "conflicting checksums"
+	LintNoGoMod     = "missing /go.mod line"
+	LintMissing     = "missing in go.sum"
+	LintUnreachable = "unreachable from go.mod"
+)
+
+type LintIssue struct {
+	Mod    module.Version
+	GoMod  bool // the issue concerns the /go.mod line
+	Kind   string
+	Detail string
+}
+
+func (l *LintIssue) String() string {
+	name := l.Mod.String()
+	if l.GoMod {
+		name += "/go.mod"
+	}
+
+	if l.Detail == "" {
+		return fmt.Sprintf("%s: %s", name, l.Kind)
+	}
+	return fmt.Sprintf("%s: %s (%s)", name, l.Kind, l.Detail)
+}
+
+// Check the consistency of the go.sum file(s) with the module graph of the
+// main module(s).  Modules without a verified .mod file can't be followed,
+// so their requirements aren't checked; see Graph.Missing.
+//
+// Modules that are in the build list but lack a zip checksum aren't
+// reported, since Go only records zip checksums for modules that provide
+// packages to the build.
+func (s *SumFile) Lint(opts *GraphOptions) ([]*LintIssue, *Graph, error) {
+	issues := []*LintIssue{}
+
+	for i, src := range s.Sources {
+		for _, other := range s.Sources[:i] {
+			if src.Name == other.Name && src.Version == other.Version && src.Checksum != other.Checksum {
+				issues = append(issues, &LintIssue{
+					Mod:    module.Version{Path: src.Name, Version: src.Version},
+					Kind:   LintConflict,
+					Detail: fmt.Sprintf("%s != %s", other.Checksum, src.Checksum),
+				})
+			}
+		}
+
+		gomod := seq.ContainsBy(s.ModFiles, func(m *ModFile) bool {
+			return m.Name == src.Name && m.Version == src.Version
+		})
+		if !gomod {
+			issues = append(issues, &LintIssue{
+				Mod:  module.Version{Path: src.Name, Version: src.Version},
+				Kind: LintNoGoMod,
+			})
+		}
+	}
+
+	for i, m := range s.ModFiles {
+		for _, other := range s.ModFiles[:i] {
+			if m.Name == other.Name && m.Version == other.Version && m.Checksum != other.Checksum {
+				issues = append(issues, &LintIssue{
+					Mod:    module.Version{Path: m.Name, Version: m.Version},
+					GoMod:  true,
+					Kind:   LintConflict,
+					Detail: fmt.Sprintf("%s != %s", other.Checksum, m.Checksum),
+				})
+			}
+		}
+	}
+
+	graph, err := s.Graph(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, mod := range graph.Unlisted {
+		if graph.Needed(mod) {
+			issues = append(issues, &LintIssue{Mod: mod, GoMod: true, Kind: LintMissing})
+		}
+	}
+
+	reported := []module.Version{}
+	for _, entry := range graph.Classify(s) {
+		if entry.Status != SumUnneeded || seq.Contains(reported, entry.Mod) {
+			continue
+		}
+		reported = append(reported, entry.Mod)
+
+		issues = append(issues, &LintIssue{Mod: entry.Mod, GoMod: entry.GoMod, Kind: LintUnreachable})
+	}
+
+	sort.SliceStable(issues, func(i int, j int) bool {
+		return lessVersion(issues[i].Mod, issues[j].Mod)
+	})
+	return issues, graph, nil
+}
@@ -2,6 +2,7 @@ package mod
 
 import (
 	"fmt"
+	"io"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -17,47 +18,92 @@ import (
 	"github.com/illikainen/go-utils/src/logging"
 	"github.com/pkg/errors"
 	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
 )
 
 type ModFile struct {
-	Name      string // e.g. github.com/BurntSushi/toml
-	Version   string // e.g. v1.3.2
-	Checksum  string // e.g. CxXYINrC8qIiEnFrOxCa7Jy5BFHlXnUU2pbicEuybxQ=
-	GoPath    string // e.g. $HOME/go
-	sigPath   string // e.g. $HOME/.cache/gofer/mod
-	InfoFiles []*InfoFile
-	log       logging.Logger
-	verified  bool
+	Name       string // e.g. github.com/BurntSushi/toml
+	Version    string // e.g. v1.3.2
+	Checksum   string // e.g. CxXYINrC8qIiEnFrOxCa7Jy5BFHlXnUU2pbicEuybxQ=
+	GoPath     string // e.g. $HOME/go
+	sigPath    string // e.g. $HOME/.cache/gofer/mod
+	origins    map[string]string
+	InfoFiles  []*InfoFile
+	Require    []module.Version // set once the file has been verified
+	GoVersion  string           // set once the file has been verified, empty if unspecified
+	Retract    []*Retraction    // set once the file has been verified or parsed
+	Deprecated string           // set once the file has been verified or parsed, empty if not deprecated
+	log        logging.Logger
+	verified   bool
 }
 
 func (m *ModFile) Verify(file string) error {
-	err := h1.VerifyMod(file, m.Checksum)
+	data, err := iofs.ReadFile(file)
 	if err != nil {
 		return err
 	}
 
-	err = m.parse(file)
+	return m.verifyData(file, data)
+}
+
+func (m *ModFile) verifyData(name string, data []byte) error {
+	cksum, err := h1.HashModData(data)
+	if err != nil {
+		return err
+	}
+
+	if cksum != m.Checksum {
+		return errors.Errorf("%s: bad checksum: %s != %s", name, cksum, m.Checksum)
+	}
+
+	err = m.parse(name, data)
 	if err != nil {
 		return err
 	}
 
 	m.verified = true
-	m.log.Tracef("%s: successfully verified %s", file, m.Checksum)
+	m.log.Tracef("%s: successfully verified %s", name, m.Checksum)
 	return nil
 }
 
-func (m *ModFile) parse(file string) error {
-	mod, err := ParseMod(file)
+func (m *ModFile) verifyStream(r io.Reader, _ *os.File) error {
+	data, err := io.ReadAll(r)
 	if err != nil {
 		return err
 	}
 
-	m.InfoFiles = m.InfoFiles[:]
+	return m.verifyData(m.String(), data)
+}
+
+func (m *ModFile) parse(name string, data []byte) error {
+	mod, err := modfile.Parse(name, data, nil)
+	if err != nil {
+		return err
+	}
+
+	m.Require = nil
+	m.GoVersion = ""
+	if mod.Go != nil {
+		m.GoVersion = mod.Go.Version
+	}
+
+	m.Deprecated = ""
+	if mod.Module != nil {
+		m.Deprecated = mod.Module.Deprecated
+	}
+
+	m.Retract = nil
+	for _, r := range mod.Retract {
+		m.Retract = append(m.Retract, &Retraction{Low: r.Low, High: r.High, Rationale: r.Rationale})
+	}
+
+	m.InfoFiles = nil
 	m.InfoFiles = append(m.InfoFiles, &InfoFile{
 		Name:    m.Name,
 		Version: m.Version,
 		GoPath:  m.GoPath,
 		sigPath: m.sigPath,
+		origins: m.origins,
 		log:     m.log,
 	})
 
@@ -77,12 +123,43 @@ func (m *ModFile) parse(file string) error {
 			Version: version,
 			GoPath:  m.GoPath,
 			sigPath: m.sigPath,
+			origins: m.origins,
 			log:     m.log,
 		})
+		m.Require = append(m.Require, module.Version{Path: name, Version: version})
 	}
 	return nil
 }
 
+// Verify the .mod file in the signed blob at SigPath() without writing it
+// anywhere.
+func (m *ModFile) VerifySigned(keyring *blob.Keyring) error {
+	data, err := readSigned(m.SigPath(), keyring)
+	if err != nil {
+		return err
+	}
+
+	return m.verifyData(m.SigPath(), data)
+}
+
+// Parse the .mod file in the signed blob at SigPath() for a version that
+// isn't in a go.sum file.  The signature is trusted in lieu of a checksum,
+// so the file can't be signed again.  The signature doesn't cover the file
+// name, so the module path in the file has to match.
+func (m *ModFile) ParseSigned(keyring *blob.Keyring) error {
+	data, err := readSigned(m.SigPath(), keyring)
+	if err != nil {
+		return errors.Wrap(err, m.SigPath())
+	}
+
+	path := modfile.ModulePath(data)
+	if path != m.Name {
+		return errors.Errorf("%s: module path %s != %s", m.SigPath(), path, m.Name)
+	}
+
+	return m.parse(m.SigPath(), data)
+}
+
 func (m *ModFile) Sign(src string, dst string, keyring *blob.Keyring) (err error) {
 	if !m.verified {
 		return errors.Errorf("%s has not been verified", src)
@@ -120,96 +197,18 @@ func (m *ModFile) Sign(src string, dst string, keyring *blob.Keyring) (err error
 
 func (m *ModFile) DownloadAndVerify(uri *url.URL, sigOutput string, goOutput string,
 	keyring *blob.Keyring) (signer cryptor.PublicKey, verified string, err error) {
-	tmp, tmpRm, err := iofs.MkdirTemp()
-	if err != nil {
-		return nil, "", err
-	}
-	defer errorx.Defer(tmpRm, &err)
-
-	sigPathExists, err := iofs.Exists(sigOutput)
+	signer, err = fetchSigned(uri, sigOutput, goOutput, keyring, m.verifyStream, false)
 	if err != nil {
 		return nil, "", err
 	}
-
-	if !sigPathExists {
-		tmpSigPath := filepath.Join(tmp, "tmp-sig")
-		tmpSig, err := os.OpenFile(tmpSigPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600) // #nosec G304
-		if err != nil {
-			return nil, "", err
-		}
-		defer errorx.Defer(tmpSig.Close, &err)
-
-		blobber, err := blob.Download(uri, tmpSig, &blob.Options{
-			Type:      metadata.Name(),
-			Keyring:   keyring,
-			Encrypted: false,
-		})
-		if err != nil {
-			return nil, "", err
-		}
-
-		tmpModPath := filepath.Join(tmp, "tmp-mod")
-		err = iofs.Copy(tmpModPath, blobber)
-		if err != nil {
-			return nil, "", err
-		}
-
-		err = m.Verify(tmpModPath)
-		if err != nil {
-			return nil, "", err
-		}
-
-		err = iofs.MoveFile(tmpSigPath, sigOutput)
-		if err != nil {
-			return nil, "", err
-		}
-	}
-
-	sig, err := os.Open(sigOutput) // #nosec G304
-	if err != nil {
-		return nil, "", err
-	}
-	defer errorx.Defer(sig.Close, &err)
-
-	blobber, err := blob.NewReader(sig, &blob.Options{
-		Type:      metadata.Name(),
-		Keyring:   keyring,
-		Encrypted: false,
-	})
-	if err != nil {
-		return nil, "", err
-	}
-	m.log.Tracef("%s: signed by: %s", sigOutput, blobber.Signer)
-
-	tmpModPath := filepath.Join(tmp, "mod")
-	err = iofs.Copy(tmpModPath, blobber)
-	if err != nil {
-		return nil, "", err
-	}
-
-	err = m.Verify(tmpModPath)
-	if err != nil {
-		return nil, "", err
-	}
-
-	modPathExists, err := iofs.Exists(goOutput)
-	if err != nil {
-		return nil, "", err
-	}
-
-	if !modPathExists {
-		err := iofs.MoveFile(tmpModPath, goOutput)
-		if err != nil {
-			return nil, "", err
-		}
-	}
+	m.log.Tracef("%s: signed by: %s", sigOutput, signer)
 
 	err = m.Verify(goOutput)
 	if err != nil {
 		return nil, "", err
 	}
 
-	return blobber.Signer, m.Checksum, nil
+	return signer, m.Checksum, nil
 }
 
 // Name of the signed .mod file.
@@ -0,0 +1,118 @@
+package mod
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Repository URL templates for module path prefixes.  {n} is replaced with
+// the n:th path element after the prefix, and the remaining elements are
+// the expected subdirectory in the repository.  Vanity domains are
+// configured with the Origins table in the configuration file.
+var defaultOrigins = map[string]string{
+	"github.com":                 "https://github.com/{1}/{2}",
+	"golang.org/x":               "https://go.googlesource.com/{1}",
+	"cloud.google.com/go":        "https://github.com/googleapis/google-cloud-go",
+	"dario.cat/mergo":            "https://github.com/imdario/mergo",
+	"go.uber.org":                "https://github.com/uber-go/{1}",
+	"go.uber.org/mock":           "https://github.com/uber/mock",
+	"go.uber.org/yarpc":          "https://github.com/yarpc/yarpc-go",
+	"google.golang.org/api":      "https://github.com/googleapis/google-api-go-client",
+	"google.golang.org/genproto": "https://github.com/googleapis/go-genproto",
+	"google.golang.org/grpc":     "https://github.com/grpc/grpc-go",
+	"google.golang.org/protobuf": "https://go.googlesource.com/protobuf",
+	"honnef.co/go/tools":         "https://github.com/dominikh/go-tools",
+	"k8s.io":                     "https://github.com/kubernetes/{1}",
+	"rsc.io":                     "https://github.com/rsc/{1}",
+}
+
+var majorSuffix = regexp.MustCompile(`(^|/)v[0-9]+$`)
+
+var errNoOrigin = errors.New("no origin mapping for the module path")
+
+// Verify that the origin repository and subdirectory in the .info file
+// correspond to the module path.  Files without an origin URL can't be
+// checked, and errNoOrigin is returned for module paths without a mapping.
+func (c *Info) verifyOrigin(name string, origins map[string]string) error {
+	if c.Origin.URL == "" {
+		return nil
+	}
+
+	url, subdir, err := expectedOrigin(name, origins)
+	if err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(strings.TrimSuffix(c.Origin.URL, ".git"), url) {
+		return errors.Errorf("%s: origin.url %s != %s", name, c.Origin.URL, url)
+	}
+
+	// Major versions are either in a subdirectory or on a branch.
+	if c.Origin.Subdir != subdir && c.Origin.Subdir != majorSuffix.ReplaceAllString(subdir, "") {
+		return errors.Errorf("%s: origin.subdir %s != %s", name, c.Origin.Subdir, subdir)
+	}
+	return nil
+}
+
+func expectedOrigin(name string, origins map[string]string) (url string, subdir string, err error) {
+	if strings.HasPrefix(name, "gopkg.in/") {
+		return gopkgOrigin(name)
+	}
+
+	prefix := ""
+	template := ""
+	for _, table := range []map[string]string{defaultOrigins, origins} {
+		for p, t := range table {
+			if (name == p || strings.HasPrefix(name, p+"/")) && len(p) >= len(prefix) {
+				prefix = p
+				template = t
+			}
+		}
+	}
+	if prefix == "" {
+		return "", "", errors.Wrap(errNoOrigin, name)
+	}
+
+	rest := strings.Split(strings.TrimPrefix(strings.TrimPrefix(name, prefix), "/"), "/")
+	if rest[0] == "" {
+		rest = nil
+	}
+
+	used := 0
+	var rerr error
+	url = regexp.MustCompile(`\{[0-9]+\}`).ReplaceAllStringFunc(template, func(s string) string {
+		n, err := strconv.Atoi(s[1 : len(s)-1])
+		if err != nil || n < 1 || n > len(rest) {
+			rerr = errors.Errorf("%s: invalid origin mapping %s for %s", name, template, prefix)
+			return s
+		}
+		if n > used {
+			used = n
+		}
+		return rest[n-1]
+	})
+	if rerr != nil {
+		return "", "", rerr
+	}
+
+	return url, strings.Join(rest[used:], "/"), nil
+}
+
+// gopkg.in/pkg.vN is github.com/go-pkg/pkg and gopkg.in/user/pkg.vN is
+// github.com/user/pkg.
+func gopkgOrigin(name string) (string, string, error) {
+	elts := strings.Split(strings.TrimPrefix(name, "gopkg.in/"), "/")
+	pkg := regexp.MustCompile(`\.v[0-9]+(-unstable)?$`).ReplaceAllString(elts[len(elts)-1], "")
+
+	switch len(elts) {
+	case 1:
+		return fmt.Sprintf("https://github.com/go-%s/%s", pkg, pkg), "", nil
+	case 2:
+		return fmt.Sprintf("https://github.com/%s/%s", elts[0], pkg), "", nil
+	}
+	return "", "", errors.Errorf("%s: invalid gopkg.in path", name)
+}
@@ -0,0 +1,218 @@
+package mod
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/illikainen/go-cryptor/src/blob"
+	"github.com/illikainen/go-utils/src/fn"
+	"github.com/illikainen/go-utils/src/iofs"
+	"github.com/illikainen/go-utils/src/seq"
+	"github.com/pkg/errors"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+type Update struct {
+	Name      string
+	Version   string
+	Kind      string // major, minor, patch, prerelease or pseudo
+	Published string // from the .info file, empty if unknown
+	Signed    bool   // the signature directory has a .mod file signed by the keyring
+	Mirrored  bool   // the version is listed in the mirror
+}
+
+func (u *Update) String() string {
+	return fmt.Sprintf("%s@%s", u.Name, u.Version)
+}
+
+type Outdated struct {
+	Name    string
+	Version string    // highest version in the go.sum file(s)
+	Updates []*Update // newer versions of the module
+	Majors  []*Update // latest version of every newer major version of the module
+}
+
+// Find newer versions of every module with code in the go.sum file(s).
+// Versions are looked up in the signature directory and, if mirror isn't
+// empty, in the @v/list files of a directory laid out according to the
+// GOPROXY protocol.  The network is never accessed.
+func (s *SumFile) Outdated(mirror string, keyring *blob.Keyring) ([]*Outdated, error) {
+	signed, err := signedVersions(s.sigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	current := map[string]string{}
+	for _, src := range s.Sources {
+		if semver.Compare(src.Version, current[src.Name]) > 0 {
+			current[src.Name] = src.Version
+		}
+	}
+
+	names := []string{}
+	for name := range current {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := []*Outdated{}
+	for _, name := range names {
+		o := &Outdated{Name: name, Version: current[name], Updates: []*Update{}, Majors: []*Update{}}
+
+		o.Updates, err = s.updates(name, o.Version, signed[name], mirror, keyring)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range o.Updates {
+			u.Kind = updateKind(o.Version, u.Version)
+		}
+
+		majors, err := s.majorUpdates(name, o.Version, signed, mirror, keyring)
+		if err != nil {
+			return nil, err
+		}
+		o.Majors = majors
+
+		if len(o.Updates) > 0 || len(o.Majors) > 0 {
+			result = append(result, o)
+		}
+	}
+
+	return result, nil
+}
+
+// Latest version of every major version above the current one, e.g.
+// example.com/foo/v3 for example.com/foo/v2.  Major versions are usually
+// consecutive, so the search stops at the first one that isn't available.
+func (s *SumFile) majorUpdates(name string, version string, signed map[string][]string, mirror string,
+	keyring *blob.Keyring) ([]*Update, error) {
+	prefix, _, ok := module.SplitPathVersion(name)
+	if !ok {
+		return nil, errors.Errorf("invalid name: %s", name)
+	}
+	sep := fn.Ternary(strings.HasPrefix(name, "gopkg.in/"), ".", "/")
+
+	majors := []*Update{}
+	for major := maxInt(semverMajor(version), 1) + 1; ; major++ {
+		majorName := fmt.Sprintf("%s%sv%d", prefix, sep, major)
+		updates, err := s.updates(majorName, "", signed[majorName], mirror, keyring)
+		if err != nil {
+			return nil, err
+		}
+		if len(updates) == 0 {
+			return majors, nil
+		}
+
+		versions := []string{}
+		for _, u := range updates {
+			versions = append(versions, u.Version)
+		}
+		latest, _ := latestVersion(versions)
+		u, _ := seq.FindBy(updates, func(u *Update) bool { return u.Version == latest })
+		u.Kind = "major"
+		majors = append(majors, u)
+	}
+}
+
+// Signed and mirrored versions of a module above since, sorted by semver.
+func (s *SumFile) updates(name string, since string, signed []string, mirror string,
+	keyring *blob.Keyring) ([]*Update, error) {
+	mirrored := []string{}
+	if mirror != "" {
+		listed, err := readList(filepath.Join(mirror, downcase(name), "@v", "list"))
+		if err != nil {
+			return nil, err
+		}
+		mirrored = listed
+	}
+
+	versions := seq.FilterBy(seq.Uniq(append(append([]string{}, signed...), mirrored...)),
+		func(version string, _ int) bool {
+			return semver.Compare(version, since) > 0
+		})
+	semver.Sort(versions)
+
+	updates := []*Update{}
+	for _, version := range versions {
+		u := &Update{
+			Name:     name,
+			Version:  version,
+			Signed:   seq.Contains(signed, version),
+			Mirrored: seq.Contains(mirrored, version),
+		}
+
+		info := s.newInfoFile(name, version)
+		switch {
+		case u.Signed:
+			// The file name alone doesn't make the version signed.
+			err := s.newModFile(name, version).ParseSigned(keyring)
+			if err != nil {
+				return nil, err
+			}
+
+			exists, err := iofs.Exists(info.SigPath())
+			if err != nil {
+				return nil, err
+			}
+			if exists {
+				err = info.VerifySigned(keyring)
+				if err != nil {
+					return nil, err
+				}
+			}
+		case u.Mirrored:
+			infoPath := filepath.Join(mirror, downcase(name), "@v", info.InfoName())
+			exists, err := iofs.Exists(infoPath)
+			if err != nil {
+				return nil, err
+			}
+			if exists {
+				err = info.Verify(infoPath)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		if info.Info != nil {
+			if info.Info.Version != version {
+				return nil, errors.Errorf("%s: version mismatch: %s", info, info.Info.Version)
+			}
+			u.Published = info.Info.Time
+		}
+		updates = append(updates, u)
+	}
+
+	return updates, nil
+}
+
+func updateKind(from string, to string) string {
+	switch {
+	case module.IsPseudoVersion(to):
+		return "pseudo"
+	case semver.Prerelease(to) != "":
+		return "prerelease"
+	case semver.Major(to) != semver.Major(from):
+		return "major"
+	case semver.MajorMinor(to) != semver.MajorMinor(from):
+		return "minor"
+	default:
+		return "patch"
+	}
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func semverMajor(version string) int {
+	major := 0
+	_, _ = fmt.Sscanf(semver.Major(version), "v%d", &major)
+	return major
+}
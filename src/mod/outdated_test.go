@@ -0,0 +1,186 @@
+package mod
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/illikainen/gofer/src/metadata"
+
+	"github.com/illikainen/go-cryptor/src/asymmetric"
+	"github.com/illikainen/go-cryptor/src/blob"
+	"github.com/illikainen/go-cryptor/src/cryptor"
+	"github.com/illikainen/go-utils/src/errorx"
+)
+
+var testKeys struct {
+	once    sync.Once
+	keyring *blob.Keyring
+	err     error
+}
+
+// Keys are expensive to generate, so every test shares one keyring.
+func newTestKeyring(t *testing.T) *blob.Keyring {
+	t.Helper()
+
+	testKeys.once.Do(func() {
+		pub, priv, err := asymmetric.GenerateKey(0)
+		testKeys.keyring = &blob.Keyring{Public: []cryptor.PublicKey{pub}, Private: priv}
+		testKeys.err = err
+	})
+	if testKeys.err != nil {
+		t.Fatal(testKeys.err)
+	}
+	return testKeys.keyring
+}
+
+func writeTestSigned(t *testing.T, keyring *blob.Keyring, path string, data []byte) {
+	t.Helper()
+
+	err := func() (err error) {
+		f, err := os.Create(path) // #nosec G304
+		if err != nil {
+			return err
+		}
+		defer errorx.Defer(f.Close, &err)
+
+		blobber, err := blob.NewWriter(f, &blob.Options{Type: metadata.Name(), Keyring: keyring})
+		if err != nil {
+			return err
+		}
+		defer errorx.Defer(blobber.Close, &err)
+
+		_, err = blobber.Write(data)
+		return err
+	}()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Write a signed .mod file for every version of name to sigPath.
+func writeTestSignedMods(t *testing.T, keyring *blob.Keyring, sigPath string, name string,
+	mods map[string]string) {
+	t.Helper()
+
+	for version, extra := range mods {
+		m := &ModFile{Name: name, Version: version, sigPath: sigPath}
+		writeTestSigned(t, keyring, m.SigPath(), []byte(fmt.Sprintf("module %s\n\ngo 1.19\n%s", name, extra)))
+	}
+}
+
+// Write the @v/list file of name in a GOPROXY directory.
+func writeTestList(t *testing.T, mirror string, name string, versions ...string) {
+	t.Helper()
+
+	dir := filepath.Join(mirror, downcase(name), "@v")
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, "list"), []byte(strings.Join(versions, "\n")+"\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateKind(t *testing.T) {
+	tests := []struct {
+		from string
+		to   string
+		want string
+	}{
+		{"v1.0.0", "v1.0.1", "patch"},
+		{"v1.0.0", "v1.1.0", "minor"},
+		{"v1.0.0", "v2.0.0", "major"},
+		{"v1.0.0", "v1.1.0-rc.1", "prerelease"},
+		{"v1.0.0", "v1.0.1-0.20200101000000-abcdefabcdef", "pseudo"},
+		{"v1.0.0", "v2.0.0+incompatible", "major"},
+	}
+
+	for _, test := range tests {
+		got := updateKind(test.from, test.to)
+		if got != test.want {
+			t.Errorf("updateKind(%q, %q) = %q, want %q", test.from, test.to, got, test.want)
+		}
+	}
+}
+
+func TestOutdated(t *testing.T) {
+	keyring := newTestKeyring(t)
+	sigPath := t.TempDir()
+	mirror := t.TempDir()
+
+	m := newTestModule(t, "example.com/m", "v1.1.0", "")
+	yaml := newTestModule(t, "gopkg.in/yaml.v2", "v2.4.0", "")
+	sum, err := ReadGoSum(&SumOptions{SumData: [][]byte{m.GoSum(), yaml.GoSum()}, SigPath: sigPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestSignedMods(t, keyring, sigPath, "example.com/m", map[string]string{"v1.1.1": "", "v1.2.0": ""})
+	writeTestList(t, mirror, "example.com/m",
+		"v1.0.0", "v1.2.0", "v1.2.1-0.20200101000000-abcdefabcdef", "v1.3.0-rc.1")
+
+	// v4 is skipped since v3 isn't available.
+	writeTestSignedMods(t, keyring, sigPath, "example.com/m/v2", map[string]string{"v2.0.0": ""})
+	writeTestList(t, mirror, "example.com/m/v2", "v2.0.0", "v2.1.0")
+	writeTestList(t, mirror, "example.com/m/v4", "v4.0.0")
+
+	writeTestSignedMods(t, keyring, sigPath, "gopkg.in/yaml.v3", map[string]string{"v3.0.1": ""})
+
+	outdated, err := sum.Outdated(mirror, keyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, o := range outdated {
+		for _, u := range o.Updates {
+			got = append(got, fmt.Sprintf("%s@%s %s %v %v", u.Name, u.Version, u.Kind, u.Signed, u.Mirrored))
+		}
+		for _, u := range o.Majors {
+			got = append(got, fmt.Sprintf("%s@%s %s %v %v", u.Name, u.Version, u.Kind, u.Signed, u.Mirrored))
+		}
+	}
+
+	want := []string{
+		"example.com/m@v1.1.1 patch true false",
+		"example.com/m@v1.2.0 minor true true",
+		"example.com/m@v1.2.1-0.20200101000000-abcdefabcdef pseudo false true",
+		"example.com/m@v1.3.0-rc.1 prerelease false true",
+		"example.com/m/v2@v2.1.0 major false true",
+		"gopkg.in/yaml.v3@v3.0.1 major true false",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestOutdatedBadSignature(t *testing.T) {
+	keyring := newTestKeyring(t)
+	sigPath := t.TempDir()
+
+	m := newTestModule(t, "example.com/m", "v1.1.0", "")
+	sum, err := ReadGoSum(&SumOptions{SumData: [][]byte{m.GoSum()}, SigPath: sigPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A signed .mod file of another module under the name of a newer version.
+	writeTestSignedMods(t, keyring, sigPath, "example.com/other", map[string]string{"v1.2.0": ""})
+	err = os.Rename(filepath.Join(sigPath, "example.com@other@v1.2.0.mod.gopkg"),
+		filepath.Join(sigPath, "example.com@m@v1.2.0.mod.gopkg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = sum.Outdated("", keyring)
+	if err == nil || !strings.Contains(err.Error(), "module path") {
+		t.Fatalf("expected a module path mismatch, got %v", err)
+	}
+}
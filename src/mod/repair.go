@@ -0,0 +1,183 @@
+package mod
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/illikainen/go-cryptor/src/blob"
+	"github.com/illikainen/go-utils/src/errorx"
+	"github.com/illikainen/go-utils/src/iofs"
+	"github.com/illikainen/go-utils/src/seq"
+)
+
+// Replace GOPATH files and directories that fail verification with the
+// content of their signed counterparts in the signature directory.  Files
+// that don't exist in GOPATH are left alone, and every replaced path is
+// returned.
+func (s *SumFile) Repair(keyring *blob.Keyring) (repaired []string, err error) {
+	for _, src := range s.Sources {
+		exists, err := iofs.Exists(src.ZipPath())
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			err := src.Verify(src.ZipPath(), ZipMode)
+			if err != nil {
+				s.log.Warnf("%s: %s", src.ZipPath(), err)
+
+				err := src.restoreZip(keyring)
+				if err != nil {
+					return nil, err
+				}
+				s.log.Infof("%s: replaced with the content of %s", src.ZipPath(), src.SigPath())
+				repaired = append(repaired, src.ZipPath())
+			}
+		}
+
+		exists, err = iofs.Exists(src.DirPath())
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			err := src.Verify(src.DirPath(), DirMode)
+			if err != nil {
+				s.log.Warnf("%s: %s", src.DirPath(), err)
+
+				err := src.restoreDir(keyring)
+				if err != nil {
+					return nil, err
+				}
+				s.log.Infof("%s: replaced with the content of %s", src.DirPath(), src.SigPath())
+				repaired = append(repaired, src.DirPath())
+			}
+		}
+	}
+
+	seen := []string{}
+	for _, m := range s.ModFiles {
+		exists, err := iofs.Exists(m.ModPath())
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			err := m.Verify(m.ModPath())
+			if err != nil {
+				s.log.Warnf("%s: %s", m.ModPath(), err)
+
+				_, err := fetchSigned(nil, m.SigPath(), m.ModPath(), keyring, m.verifyStream, true)
+				if err != nil {
+					return nil, err
+				}
+
+				err = m.Verify(m.ModPath())
+				if err != nil {
+					return nil, err
+				}
+				s.log.Infof("%s: replaced with the content of %s", m.ModPath(), m.SigPath())
+				repaired = append(repaired, m.ModPath())
+			}
+		}
+
+		for _, i := range m.InfoFiles {
+			if seq.Contains(seen, i.String()) {
+				continue
+			}
+			seen = append(seen, i.String())
+
+			exists, err := iofs.Exists(i.InfoPath())
+			if err != nil {
+				return nil, err
+			}
+			if !exists {
+				continue
+			}
+
+			err = i.Verify(i.InfoPath())
+			if err != nil {
+				s.log.Warnf("%s: %s", i.InfoPath(), err)
+
+				_, err := fetchSigned(nil, i.SigPath(), i.InfoPath(), keyring, i.verifyStream, true)
+				if err != nil {
+					return nil, err
+				}
+
+				err = i.Verify(i.InfoPath())
+				if err != nil {
+					return nil, err
+				}
+				s.log.Infof("%s: replaced with the content of %s", i.InfoPath(), i.SigPath())
+				repaired = append(repaired, i.InfoPath())
+			}
+		}
+	}
+
+	return repaired, nil
+}
+
+func (s *Source) restoreZip(keyring *blob.Keyring) error {
+	_, err := fetchSigned(nil, s.SigPath(), s.ZipPath(), keyring, s.verifyStream, true)
+	if err != nil {
+		return err
+	}
+
+	err = writeFile(s.ZipHashPath(), []byte(s.Checksum))
+	if err != nil {
+		return err
+	}
+
+	return s.Verify(s.ZipPath(), ZipMode)
+}
+
+// The extracted directory is recreated from the zip in GOPATH if it's
+// intact, and otherwise from a temporary copy of the signed zip.  The
+// directory is only removed once a verified zip is available.
+func (s *Source) restoreDir(keyring *blob.Keyring) (err error) {
+	zipPath := s.ZipPath()
+	if s.Verify(zipPath, ZipMode) != nil {
+		tmp, tmpRm, err := iofs.MkdirTemp()
+		if err != nil {
+			return err
+		}
+		defer errorx.Defer(tmpRm, &err)
+
+		zipPath = filepath.Join(tmp, s.ZipName())
+		_, err = fetchSigned(nil, s.SigPath(), zipPath, keyring, s.verifyStream, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = removeReadOnlyDir(s.DirPath())
+	if err != nil {
+		return err
+	}
+
+	return s.extract(zipPath)
+}
+
+// Extracted modules are read-only, so their directories must be made
+// writable before their content can be removed.
+func removeReadOnlyDir(dir string) error {
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		return os.Chmod(path, info.Mode().Perm()|0700)
+	})
+	if err != nil {
+		return err
+	}
+
+	return os.RemoveAll(dir)
+}
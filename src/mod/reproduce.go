@@ -0,0 +1,114 @@
+package mod
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/illikainen/gofer/src/git"
+	"github.com/illikainen/gofer/src/h1"
+
+	"github.com/illikainen/go-utils/src/errorx"
+	"github.com/illikainen/go-utils/src/iofs"
+	"github.com/pkg/errors"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/zip"
+)
+
+type ReproduceResult struct {
+	Revision string // tag or pseudo-version revision
+	Commit   string
+	Subdir   string
+	Checksum string // h1 of the rebuilt zip
+	Match    bool   // whether Checksum matches go.sum
+}
+
+// Rebuild the module zip for src from a local clone or mirror of its
+// repository and compare its h1 with go.sum.  The zip is created from git
+// archive the same way as the Go command does it, so a match is evidence
+// that the zip served by a proxy corresponds to the upstream source.
+//
+// The subdirectory is derived from the module path unless it's specified.
+func (s *SumFile) Reproduce(src *Source, repo string, subdir string) (result *ReproduceResult, err error) {
+	tmp, tmpRm, err := iofs.MkdirTemp()
+	if err != nil {
+		return nil, err
+	}
+	defer errorx.Defer(tmpRm, &err)
+
+	// zip.CreateFromVCS() requires a non-bare repository.
+	clone := git.NewClient(&git.Options{Dir: filepath.Join(tmp, "repo")})
+	err = clone.Clone(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := []string{subdir}
+	if subdir == "" {
+		_, expected, err := expectedOrigin(src.Name, s.origins)
+		if err != nil {
+			return nil, err
+		}
+		candidates = []string{expected, majorSuffix.ReplaceAllString(expected, "")}
+	}
+
+	// Tags for modules in subdirectories are prefixed with the
+	// subdirectory without any major version suffix.
+	result = &ReproduceResult{Revision: strings.TrimSuffix(src.Version, "+incompatible")}
+	prefix := majorSuffix.ReplaceAllString(candidates[len(candidates)-1], "")
+	if prefix != "" {
+		result.Revision = prefix + "/" + result.Revision
+	}
+	if module.IsPseudoVersion(src.Version) {
+		result.Revision, err = module.PseudoVersionRev(src.Version)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result.Commit, err = clone.CommitHash(result.Revision + "^{commit}")
+	if err != nil {
+		return nil, errors.Errorf("%s: %s isn't available in %s", src, result.Revision, repo)
+	}
+
+	info, err := s.info(src.Name, src.Version)
+	if err == nil && info.Origin.Hash != "" && info.Origin.Hash != result.Commit {
+		return nil, errors.Errorf("%s: %s is %s, but the .info file claims %s", src, result.Revision,
+			result.Commit, info.Origin.Hash)
+	}
+
+	result.Subdir = candidates[len(candidates)-1]
+	for _, candidate := range candidates {
+		if clone.Exists(result.Commit, path.Join(candidate, "go.mod")) {
+			result.Subdir = candidate
+			break
+		}
+	}
+
+	archive := filepath.Join(tmp, src.ZipName())
+	f, err := os.Create(archive) // #nosec G304
+	if err != nil {
+		return nil, err
+	}
+	defer errorx.Defer(f.Close, &err)
+
+	err = zip.CreateFromVCS(f, module.Version{Path: src.Name, Version: src.Version}, clone.Dir, result.Commit,
+		result.Subdir)
+	if err != nil {
+		return nil, err
+	}
+
+	err = f.Sync()
+	if err != nil {
+		return nil, err
+	}
+
+	result.Checksum, err = h1.HashZip(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Match = result.Checksum == src.Checksum
+	return result, nil
+}
@@ -0,0 +1,201 @@
+package mod
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/illikainen/gofer/src/config"
+
+	"github.com/illikainen/go-cryptor/src/blob"
+	"github.com/illikainen/go-utils/src/seq"
+	"github.com/pkg/errors"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+const (
+	NoticeRetracted  = "retracted"
+	NoticeDeprecated = "deprecated"
+)
+
+// Retract directive in a .mod file.  Low and High are equal for a single
+// version.
+type Retraction struct {
+	Low       string
+	High      string
+	Rationale string
+}
+
+func (r *Retraction) Contains(version string) bool {
+	return semver.Compare(version, r.Low) >= 0 && semver.Compare(version, r.High) <= 0
+}
+
+// Retracted version or deprecated module in a go.sum file.
+type Notice struct {
+	Name        string
+	Version     string
+	Kind        string // NoticeRetracted or NoticeDeprecated
+	Reason      string // rationale or deprecation message, may be empty
+	Latest      string // version of the signed .mod file with the directive
+	Replacement string // later version or module path, empty if unknown
+}
+
+func (n *Notice) String() string {
+	msg := fmt.Sprintf("%s@%s: %s by %s", n.Name, n.Version, n.Kind, n.Latest)
+	if n.Reason != "" {
+		msg += fmt.Sprintf(": %s", n.Reason)
+	}
+	if n.Replacement != "" {
+		msg += fmt.Sprintf(" (replacement: %s)", n.Replacement)
+	}
+	return msg
+}
+
+// Check every module version with code in the go.sum file(s) against the
+// retract directives and the deprecation comment of the latest signed .mod
+// file for the module, the same way as the go command does it.  Notices are
+// logged, and an error is returned if the policy is to fail.
+func (s *SumFile) CheckRetracted(policy *config.RetractedPolicy, keyring *blob.Keyring) ([]*Notice, error) {
+	signed, err := signedVersions(s.sigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	notices := []*Notice{}
+	seen := []string{}
+	for _, src := range s.Sources {
+		if seq.Contains(seen, src.String()) {
+			continue
+		}
+		seen = append(seen, src.String())
+
+		versions := signed[src.Name]
+		if len(versions) == 0 {
+			s.log.Debugf("%s: no signed .mod files", src)
+			continue
+		}
+
+		version, _ := latestVersion(versions)
+		latest := s.newModFile(src.Name, version)
+		err := latest.ParseSigned(keyring)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range latest.Retract {
+			if !r.Contains(src.Version) {
+				continue
+			}
+
+			// Pre-releases and pseudo-versions aren't suggested.
+			replacement := ""
+			for _, v := range versions {
+				release := semver.Prerelease(v) == "" && !module.IsPseudoVersion(v)
+				if release && semver.Compare(v, src.Version) > 0 && !retracted(latest.Retract, v) {
+					replacement = v
+				}
+			}
+
+			notices = append(notices, &Notice{
+				Name:        src.Name,
+				Version:     src.Version,
+				Kind:        NoticeRetracted,
+				Reason:      r.Rationale,
+				Latest:      latest.Version,
+				Replacement: replacement,
+			})
+			break
+		}
+
+		if latest.Deprecated != "" {
+			notices = append(notices, &Notice{
+				Name:        src.Name,
+				Version:     src.Version,
+				Kind:        NoticeDeprecated,
+				Reason:      latest.Deprecated,
+				Latest:      latest.Version,
+				Replacement: deprecationReplacement(src.Name, latest.Deprecated),
+			})
+		}
+	}
+
+	refused := 0
+	for _, n := range notices {
+		switch {
+		case seq.Contains(policy.Allow, n.Name) || seq.Contains(policy.Allow, n.Name+"@"+n.Version):
+			s.log.Infof("%s", n)
+		case policy.Fail:
+			s.log.Errorf("%s", n)
+			refused++
+		default:
+			s.log.Warnf("%s", n)
+		}
+	}
+
+	if refused > 0 {
+		return nil, errors.Errorf("%d retracted or deprecated module version(s)", refused)
+	}
+	return notices, nil
+}
+
+// Versions of the signed .mod files in sigPath by module path, sorted by
+// semver.
+func signedVersions(sigPath string) (map[string][]string, error) {
+	entries, err := os.ReadDir(sigPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return map[string][]string{}, nil
+		}
+		return nil, err
+	}
+
+	versions := map[string][]string{}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".mod.gopkg") {
+			continue
+		}
+		base := strings.TrimSuffix(entry.Name(), ".mod.gopkg")
+
+		i := strings.LastIndex(base, "@")
+		if i <= 0 {
+			continue
+		}
+
+		name, err := validateName(strings.ReplaceAll(base[:i], "@", "/"))
+		if err != nil {
+			return nil, err
+		}
+
+		version, _, err := validateVersion(base[i+1:])
+		if err != nil || !semver.IsValid(version) {
+			continue
+		}
+		versions[name] = append(versions[name], version)
+	}
+
+	for _, v := range versions {
+		sort.Slice(v, func(i int, j int) bool {
+			return semver.Compare(v[i], v[j]) < 0
+		})
+	}
+	return versions, nil
+}
+
+func retracted(retract []*Retraction, version string) bool {
+	return seq.ContainsBy(retract, func(r *Retraction) bool { return r.Contains(version) })
+}
+
+// Module path mentioned in a deprecation message, e.g. "use
+// example.com/foo/v2 instead".
+func deprecationReplacement(name string, msg string) string {
+	for _, word := range strings.Fields(msg) {
+		word = strings.Trim(word, ".,;:()[]\"'`")
+		if word != name && strings.Contains(word, "/") && module.CheckPath(word) == nil {
+			return word
+		}
+	}
+	return ""
+}
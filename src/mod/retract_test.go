@@ -0,0 +1,107 @@
+package mod
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/illikainen/gofer/src/config"
+)
+
+func TestRetractionContains(t *testing.T) {
+	tests := []struct {
+		r       Retraction
+		version string
+		want    bool
+	}{
+		{Retraction{Low: "v1.0.0", High: "v1.0.0"}, "v1.0.0", true},
+		{Retraction{Low: "v1.0.0", High: "v1.0.0"}, "v1.0.1", false},
+		{Retraction{Low: "v1.0.0", High: "v1.2.0"}, "v1.0.0", true},
+		{Retraction{Low: "v1.0.0", High: "v1.2.0"}, "v1.1.5", true},
+		{Retraction{Low: "v1.0.0", High: "v1.2.0"}, "v1.2.0", true},
+		{Retraction{Low: "v1.0.0", High: "v1.2.0"}, "v1.2.1", false},
+		{Retraction{Low: "v1.0.0", High: "v1.2.0"}, "v0.9.0", false},
+		{Retraction{Low: "v1.0.0", High: "v1.2.0"}, "v1.0.0-rc.1", false},
+		{Retraction{Low: "v1.0.0", High: "v1.2.0"}, "v1.2.0-rc.1", true},
+	}
+
+	for _, test := range tests {
+		got := test.r.Contains(test.version)
+		if got != test.want {
+			t.Errorf("[%s, %s].Contains(%q) = %v, want %v", test.r.Low, test.r.High, test.version, got, test.want)
+		}
+	}
+}
+
+func TestDeprecationReplacement(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want string
+	}{
+		{"", ""},
+		{"unmaintained", ""},
+		{"use example.com/m/v2 instead.", "example.com/m/v2"},
+		{"moved to (example.com/other), see https://example.com", "example.com/other"},
+		{"example.com/m is replaced by `example.com/new`", "example.com/new"},
+		{"see example.com/m for details", ""},
+		{"use foo/bar instead", ""},
+	}
+
+	for _, test := range tests {
+		got := deprecationReplacement("example.com/m", test.msg)
+		if got != test.want {
+			t.Errorf("deprecationReplacement(%q) = %q, want %q", test.msg, got, test.want)
+		}
+	}
+}
+
+func TestCheckRetracted(t *testing.T) {
+	keyring := newTestKeyring(t)
+	sigPath := t.TempDir()
+
+	m := newTestModule(t, "example.com/m", "v1.0.0", "")
+	sum, err := ReadGoSum(&SumOptions{SumData: [][]byte{m.GoSum()}, SigPath: sigPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The directives of v1.1.0 apply since pre-releases and pseudo-versions
+	// are only used if there's no release, and neither of them is suggested
+	// as a replacement.
+	writeTestSignedMods(t, keyring, sigPath, "example.com/m", map[string]string{
+		"v1.0.1":                               "",
+		"v1.1.1-0.20200101000000-abcdefabcdef": "",
+		"v1.2.0-rc.1":                          "retract v1.1.0\n",
+	})
+	mod := &ModFile{Name: "example.com/m", Version: "v1.1.0", sigPath: sigPath}
+	writeTestSigned(t, keyring, mod.SigPath(), []byte("// Deprecated: use example.com/m/v2 instead.\n"+
+		"module example.com/m\n\ngo 1.19\n\nretract [v1.0.0, v1.0.1] // broken\n"))
+
+	notices, err := sum.CheckRetracted(&config.RetractedPolicy{}, keyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, n := range notices {
+		got = append(got, n.String())
+	}
+	want := []string{
+		"example.com/m@v1.0.0: retracted by v1.1.0: broken (replacement: v1.1.0)",
+		"example.com/m@v1.0.0: deprecated by v1.1.0: use example.com/m/v2 instead. " +
+			"(replacement: example.com/m/v2)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	_, err = sum.CheckRetracted(&config.RetractedPolicy{Fail: true}, keyring)
+	if err == nil {
+		t.Fatal("expected an error with a failing policy")
+	}
+
+	policy := &config.RetractedPolicy{Fail: true, Allow: []string{"example.com/m@v1.0.0"}}
+	notices, err = sum.CheckRetracted(policy, keyring)
+	if err != nil || len(notices) != 2 {
+		t.Fatalf("unexpected result: %v, %v", notices, err)
+	}
+}
@@ -0,0 +1,265 @@
+package mod
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/illikainen/gofer/src/config"
+	"github.com/illikainen/gofer/src/metadata"
+
+	"github.com/illikainen/go-cryptor/src/blob"
+	"github.com/illikainen/go-cryptor/src/cryptor"
+	"github.com/illikainen/go-utils/src/errorx"
+	"github.com/illikainen/go-utils/src/iofs"
+	"github.com/illikainen/go-utils/src/seq"
+	"github.com/illikainen/go-utils/src/stringx"
+	"github.com/pkg/errors"
+	"golang.org/x/mod/module"
+)
+
+const (
+	ReviewApproved = "approved"
+	ReviewRejected = "rejected"
+)
+
+// Review is a signed attestation that a module version has been reviewed.
+// It's bound to the module zip by its h1.
+type Review struct {
+	Name     string    // e.g. github.com/BurntSushi/toml
+	Version  string    // e.g. v1.3.2
+	Checksum string    // h1 of the reviewed zip
+	Reviewer string    // fingerprint of the key that signed the review
+	Verdict  string    // ReviewApproved or ReviewRejected
+	Notes    string    `json:",omitempty"`
+	Base     string    `json:",omitempty"` // version the changes were reviewed against
+	Time     time.Time // when the review was recorded
+}
+
+func (r *Review) Verify() error {
+	_, err := validateName(r.Name)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = validateVersion(r.Version)
+	if err != nil {
+		return err
+	}
+
+	_, err = validateChecksum(r.Checksum)
+	if err != nil {
+		return err
+	}
+
+	if r.Base != "" {
+		_, _, err = validateVersion(r.Base)
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.Reviewer == "" || strings.ContainsAny(r.Reviewer, "\r\n") {
+		return errors.Errorf("invalid reviewer: %s", r.Reviewer)
+	}
+
+	if !seq.Contains([]string{ReviewApproved, ReviewRejected}, r.Verdict) {
+		return errors.Errorf("invalid verdict: %s", r.Verdict)
+	}
+
+	if r.Time.IsZero() {
+		return errors.Errorf("invalid time")
+	}
+	return nil
+}
+
+func (r *Review) String() string {
+	base := ""
+	if r.Base != "" {
+		base = fmt.Sprintf(" against %s", r.Base)
+	}
+	return fmt.Sprintf("%s@%s: %s by %s%s on %s", r.Name, r.Version, r.Verdict, r.Reviewer, base,
+		r.Time.UTC().Format(time.RFC3339))
+}
+
+// Sign the review and store it next to the signed zip.  The zip is
+// verified first so that the review is bound to content that matches
+// go.sum.
+func (s *Source) WriteReview(review *Review, keyring *blob.Keyring) (err error) {
+	_, err = s.ReadVerified(keyring)
+	if err != nil {
+		return err
+	}
+
+	if keyring.Private == nil {
+		return errors.Errorf("a private key is required to sign a review")
+	}
+
+	review.Name = s.Name
+	review.Version = s.Version
+	review.Checksum = s.Checksum
+	review.Reviewer = keyring.Private.Fingerprint()
+	err = review.Verify()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(review, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	staged, err := stageFile(s.ReviewPath())
+	if err != nil {
+		return err
+	}
+	defer errorx.Defer(staged.Close, &err)
+
+	blobber, err := blob.NewWriter(staged, &blob.Options{
+		Type:      metadata.Name(),
+		Keyring:   keyring,
+		Encrypted: false,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = blobber.Write(append(data, '\n'))
+	if err != nil {
+		return errorx.Join(err, blobber.Close())
+	}
+
+	err = blobber.Close()
+	if err != nil {
+		return err
+	}
+
+	return staged.Commit()
+}
+
+// Read and verify the signed review of this module version.
+func (s *Source) ReadReview(keyring *blob.Keyring) (review *Review, signer cryptor.PublicKey, err error) {
+	f, err := os.Open(s.ReviewPath()) // #nosec G304
+	if err != nil {
+		return nil, nil, err
+	}
+	defer errorx.Defer(f.Close, &err)
+
+	blobber, err := blob.NewReader(f, &blob.Options{
+		Type:      metadata.Name(),
+		Keyring:   keyring,
+		Encrypted: false,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	review, err = s.readReview(blobber)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return review, blobber.Signer, nil
+}
+
+func (s *Source) readReview(blobber *blob.Reader) (*Review, error) {
+	data, err := io.ReadAll(&payloadReader{blobber})
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(stringx.Sanitize(data), data) {
+		return nil, errors.Errorf("invalid content in %s", s.ReviewName())
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	review := &Review{}
+	err = decoder.Decode(review)
+	if err != nil {
+		return nil, err
+	}
+
+	err = review.Verify()
+	if err != nil {
+		return nil, err
+	}
+
+	if review.Name != s.Name || review.Version != s.Version {
+		return nil, errors.Errorf("%s: review of %s@%s", s.ReviewName(), review.Name, review.Version)
+	}
+
+	if review.Checksum != s.Checksum {
+		return nil, errors.Errorf("%s: bad checksum: %s != %s", s.ReviewName(), review.Checksum, s.Checksum)
+	}
+
+	if review.Reviewer != blobber.Signer.Fingerprint() {
+		return nil, errors.Errorf("%s: reviewed by %s but signed by %s", s.ReviewName(), review.Reviewer,
+			blobber.Signer.Fingerprint())
+	}
+
+	return review, nil
+}
+
+// Check every module version in the go.sum file(s) against the review
+// policy, including versions that were signed before the policy was
+// enabled.  The reviews that satisfied the policy are returned.
+func (s *SumFile) CheckReviews(policy *config.ReviewPolicy, keyring *blob.Keyring) ([]*Review, error) {
+	reviews := []*Review{}
+	failed := 0
+
+	for _, src := range s.Sources {
+		if module.MatchPrefixPatterns(strings.Join(policy.Exempt, ","), src.Name) {
+			s.log.Debugf("%s: exempt from review", src)
+			continue
+		}
+
+		exists, err := iofs.Exists(src.ReviewPath())
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			s.log.Errorf("%s: not reviewed", src)
+			failed++
+			continue
+		}
+
+		review, signer, err := src.ReadReview(keyring)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case review.Verdict != ReviewApproved:
+			s.log.Errorf("%s", review)
+			failed++
+		case len(policy.Reviewers) > 0 && !seq.Contains(policy.Reviewers, signer.Fingerprint()):
+			s.log.Errorf("%s: %s isn't an accepted reviewer", src, signer.Fingerprint())
+			failed++
+		default:
+			s.log.Infof("%s", review)
+			reviews = append(reviews, review)
+		}
+	}
+
+	if failed > 0 {
+		return nil, errors.Errorf("%d module(s) without an approved review", failed)
+	}
+	return reviews, nil
+}
+
+// Name of the signed review.
+func (s *Source) ReviewName() string {
+	return fmt.Sprintf("%s@%s.review.gopkg", strings.ReplaceAll(s.Name, "/", "@"), s.Version)
+}
+
+// Path where this utility stores the signed review.
+func (s *Source) ReviewPath() string {
+	return filepath.Join(s.sigPath, s.ReviewName())
+}
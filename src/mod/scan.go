@@ -0,0 +1,356 @@
+package mod
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/illikainen/gofer/src/config"
+
+	"github.com/illikainen/go-cryptor/src/blob"
+	"github.com/illikainen/go-utils/src/errorx"
+	"github.com/illikainen/go-utils/src/fn"
+	"github.com/illikainen/go-utils/src/iofs"
+	"github.com/illikainen/go-utils/src/seq"
+)
+
+const (
+	SeverityLow    = "low"
+	SeverityMedium = "medium"
+	SeverityHigh   = "high"
+)
+
+// Severities from lowest to highest.
+var Severities = []string{SeverityLow, SeverityMedium, SeverityHigh}
+
+const (
+	ScanExecutable    = "executable"
+	ScanBinary        = "binary"
+	ScanSharedObject  = "shared-object"
+	ScanArchive       = "archive"
+	ScanMinified      = "minified-js"
+	ScanEntropy       = "high-entropy"
+	ScanShell         = "shell-script"
+	ScanGenerate      = "go-generate-network"
+	ScanLargeTestdata = "large-testdata"
+)
+
+const (
+	scanSampleSize     = 1 << 20 // bytes read from each file
+	minEntropySize     = 4096
+	maxEntropy         = 7.2 // bits per byte
+	minifiedLineLength = 500
+	largeTestdataSize  = 5 << 20
+)
+
+var binaryMagic = []struct {
+	format string
+	magic  []byte
+}{
+	{"ELF", []byte("\x7fELF")},
+	{"PE", []byte("MZ")},
+	{"Mach-O", []byte{0xfe, 0xed, 0xfa, 0xce}},
+	{"Mach-O", []byte{0xfe, 0xed, 0xfa, 0xcf}},
+	{"Mach-O", []byte{0xce, 0xfa, 0xed, 0xfe}},
+	{"Mach-O", []byte{0xcf, 0xfa, 0xed, 0xfe}},
+	{"Mach-O universal", []byte{0xca, 0xfe, 0xba, 0xbe}},
+	{"WebAssembly", []byte("\x00asm")},
+}
+
+var archiveMagic = []struct {
+	format string
+	magic  []byte
+}{
+	{"zip", []byte("PK\x03\x04")},
+	{"zip", []byte("PK\x05\x06")},
+	{"gzip", []byte{0x1f, 0x8b}},
+	{"bzip2", []byte("BZh")},
+	{"xz", []byte("\xfd7zXZ\x00")},
+	{"7z", []byte("7z\xbc\xaf\x27\x1c")},
+	{"rar", []byte("Rar!\x1a\x07")},
+	{"zstd", []byte{0x28, 0xb5, 0x2f, 0xfd}},
+}
+
+var (
+	sharedObjectExts = []string{".so", ".dylib", ".dll"}
+	archiveExts      = []string{
+		".zip", ".jar", ".tar", ".tgz", ".gz", ".bz2", ".xz", ".7z", ".rar", ".zst", ".whl",
+	}
+	shellExts = []string{".sh", ".bash", ".zsh", ".ksh", ".ps1", ".bat", ".cmd"}
+
+	// Formats that are compressed by design.
+	mediaExts = []string{
+		".png", ".jpg", ".jpeg", ".gif", ".webp", ".ico", ".bmp", ".pdf",
+		".woff", ".woff2", ".ttf", ".otf", ".mp3", ".mp4", ".ogg", ".wav",
+	}
+
+	networkGenerate = regexp.MustCompile(`(?i)\b(curl|wget|https?://|git\s+clone|go\s+run\s+\S+@\S+)`)
+)
+
+type Finding struct {
+	Name       string
+	Version    string
+	Path       string // relative to the module root
+	Rule       string
+	Severity   string
+	Detail     string
+	Suppressed string `json:",omitempty"` // reason of a matching suppression
+}
+
+func (f *Finding) String() string {
+	msg := fmt.Sprintf("%s@%s: %s: %s [%s]", f.Name, f.Version, f.Path, f.Rule, f.Severity)
+	if f.Detail != "" {
+		msg += fmt.Sprintf(" (%s)", f.Detail)
+	}
+	return msg
+}
+
+// Rank of a severity, or -1 if it's invalid.
+func SeverityRank(severity string) int {
+	for i, s := range Severities {
+		if s == severity {
+			return i
+		}
+	}
+	return -1
+}
+
+// Scan the verified zip of every module version for content that h1 allows
+// but that deserves a closer look, such as binaries and archives.  Findings
+// that match a suppression are returned with Suppressed set.
+func (s *SumFile) Scan(keyring *blob.Keyring, suppressions []config.ScanSuppression) ([]*Finding, error) {
+	findings := []*Finding{}
+	seen := []string{}
+
+	for _, src := range s.Sources {
+		if seq.Contains(seen, src.String()) {
+			continue
+		}
+		seen = append(seen, src.String())
+
+		zipExists, err := iofs.Exists(src.ZipPath())
+		if err != nil {
+			return nil, err
+		}
+
+		sigExists, err := iofs.Exists(src.SigPath())
+		if err != nil {
+			return nil, err
+		}
+
+		if !zipExists && !sigExists {
+			s.log.Warnf("%s: not available, skipping", src)
+			continue
+		}
+
+		z, err := src.ReadVerified(keyring)
+		if err != nil {
+			return nil, err
+		}
+
+		found, err := ScanZip(z)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, f := range found {
+			f.Name = src.Name
+			f.Version = src.Version
+
+			suppression, ok := seq.FindBy(suppressions, func(sup config.ScanSuppression) bool {
+				return (sup.Module == "" || sup.Module == src.Name || sup.Module == src.String()) &&
+					(sup.Path == "" || matchGlobs(f.Path, []string{sup.Path}, false)) &&
+					(sup.Rule == "" || sup.Rule == f.Rule)
+			})
+			if ok {
+				f.Suppressed = fn.Ternary(suppression.Reason != "", suppression.Reason, "suppressed")
+			}
+		}
+		findings = append(findings, found...)
+	}
+
+	return findings, nil
+}
+
+// Scan the files in a module zip.  The module prefix is stripped from every
+// path.
+func ScanZip(z *zip.Reader) ([]*Finding, error) {
+	findings := []*Finding{}
+	files := zipFiles(z)
+
+	names := []string{}
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := files[name]
+		data, err := readZipSample(f)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, finding := range scanFile(name, f, data) {
+			finding.Path = name
+			findings = append(findings, finding)
+		}
+	}
+
+	return findings, nil
+}
+
+func scanFile(name string, f *zip.File, data []byte) []*Finding {
+	findings := []*Finding{}
+	ext := strings.ToLower(path.Ext(name))
+	testdata := strings.HasPrefix(name, "testdata/") || strings.Contains(name, "/testdata/")
+	size := int64(f.UncompressedSize64)
+
+	add := func(rule string, severity string, detail string) {
+		findings = append(findings, &Finding{Rule: rule, Severity: severity, Detail: detail})
+	}
+
+	binary := ""
+	for _, m := range binaryMagic {
+		if bytes.HasPrefix(data, m.magic) {
+			binary = m.format
+			break
+		}
+	}
+	// MZ is too short to be conclusive on its own.
+	if binary == "PE" && !isPE(data) {
+		binary = ""
+	}
+
+	archive := ""
+	for _, m := range archiveMagic {
+		if bytes.HasPrefix(data, m.magic) {
+			archive = m.format
+			break
+		}
+	}
+	if archive == "" && len(data) > 262 && bytes.Equal(data[257:262], []byte("ustar")) {
+		archive = "tar"
+	}
+
+	switch {
+	case seq.Contains(sharedObjectExts, ext) || strings.Contains(path.Base(name), ".so."):
+		add(ScanSharedObject, SeverityHigh, binary)
+	case binary != "":
+		add(ScanBinary, SeverityHigh, binary)
+	}
+
+	if f.Mode()&0o111 != 0 && !f.Mode().IsDir() {
+		add(ScanExecutable, SeverityMedium, f.Mode().String())
+	}
+
+	if archive != "" || seq.Contains(archiveExts, ext) {
+		add(ScanArchive, fn.Ternary(testdata, SeverityLow, SeverityMedium), archive)
+	}
+
+	if seq.Contains(shellExts, ext) || bytes.HasPrefix(data, []byte("#!")) {
+		interpreter, _, _ := strings.Cut(string(data[:minInt(len(data), 80)]), "\n")
+		add(ScanShell, SeverityLow, fn.Ternary(strings.HasPrefix(interpreter, "#!"), interpreter, ""))
+	}
+
+	if (ext == ".js" || ext == ".mjs" || ext == ".cjs") && size >= minEntropySize {
+		longest, average := lineLengths(data)
+		if average > minifiedLineLength {
+			add(ScanMinified, SeverityMedium, fmt.Sprintf("longest line %d bytes", longest))
+		}
+	}
+
+	if ext == ".go" {
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if strings.HasPrefix(line, "//go:generate ") && networkGenerate.MatchString(line) {
+				add(ScanGenerate, SeverityHigh, line)
+			}
+		}
+	}
+
+	if binary == "" && archive == "" && !seq.Contains(archiveExts, ext) && !seq.Contains(mediaExts, ext) &&
+		size >= minEntropySize {
+		entropy := shannonEntropy(data)
+		if entropy > maxEntropy {
+			add(ScanEntropy, fn.Ternary(testdata, SeverityLow, SeverityMedium), fmt.Sprintf("%.2f bits/byte", entropy))
+		}
+	}
+
+	if testdata && size > largeTestdataSize {
+		add(ScanLargeTestdata, SeverityMedium, fmt.Sprintf("%d bytes", size))
+	}
+
+	return findings
+}
+
+func readZipSample(f *zip.File) (data []byte, err error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer errorx.Defer(r.Close, &err)
+
+	return io.ReadAll(io.LimitReader(r, scanSampleSize))
+}
+
+func isPE(data []byte) bool {
+	if len(data) < 0x40 {
+		return false
+	}
+
+	offset := int(data[0x3c]) | int(data[0x3d])<<8 | int(data[0x3e])<<16 | int(data[0x3f])<<24
+	return offset >= 0x40 && offset+4 <= len(data) && bytes.Equal(data[offset:offset+4], []byte("PE\x00\x00"))
+}
+
+func shannonEntropy(data []byte) float64 {
+	counts := [256]int{}
+	for _, b := range data {
+		counts[b]++
+	}
+
+	entropy := 0.0
+	for _, count := range counts {
+		if count == 0 {
+			continue
+		}
+		p := float64(count) / float64(len(data))
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+func lineLengths(data []byte) (longest int, average int) {
+	lines := 0
+	total := 0
+
+	scan := bufio.NewScanner(bytes.NewReader(data))
+	scan.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for scan.Scan() {
+		n := len(scan.Bytes())
+		if n > longest {
+			longest = n
+		}
+		total += n
+		lines++
+	}
+
+	if lines == 0 {
+		return 0, 0
+	}
+	return longest, total / lines
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -3,6 +3,8 @@ package mod
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"io/fs"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -76,6 +78,25 @@ func (s *Source) Verify(path string, mode int) error {
 	return nil
 }
 
+// The h1 of a zip can't be computed without random access, so the payload is
+// hashed from the staged file once it has been written.
+func (s *Source) verifyStream(r io.Reader, staged *os.File) error {
+	_, err := io.Copy(io.Discard, r)
+	if err != nil {
+		return err
+	}
+
+	cksum, err := h1.HashZip(staged.Name())
+	if err != nil {
+		return err
+	}
+
+	if cksum != s.Checksum {
+		return errors.Errorf("%s: bad checksum: %s != %s", s, cksum, s.Checksum)
+	}
+	return nil
+}
+
 func (s *Source) Sign(src string, dst string, keyring *blob.Keyring) (err error) {
 	if !s.verified {
 		return errors.Errorf("%s has not been verified", src)
@@ -105,107 +126,87 @@ func (s *Source) Sign(src string, dst string, keyring *blob.Keyring) (err error)
 
 func (s *Source) DownloadAndVerify(uri *url.URL, sigOutput string, goOutput string, goHashOutput string,
 	keyring *blob.Keyring) (signer cryptor.PublicKey, verified string, err error) {
-	tmp, tmpRm, err := iofs.MkdirTemp()
+	signer, err = fetchSigned(uri, sigOutput, goOutput, keyring, s.verifyStream, false)
 	if err != nil {
 		return nil, "", err
 	}
-	defer errorx.Defer(tmpRm, &err)
+	s.log.Tracef("%s: signed by: %s", sigOutput, signer)
 
-	sigPathExists, err := iofs.Exists(sigOutput)
+	err = writeIfNotExists(goHashOutput, []byte(s.Checksum))
 	if err != nil {
 		return nil, "", err
 	}
 
-	if !sigPathExists {
-		tmpSigPath := filepath.Join(tmp, "tmp-sig")
-		tmpSig, err := os.OpenFile(tmpSigPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600) // #nosec G304
-		if err != nil {
-			return nil, "", err
-		}
-		defer errorx.Defer(tmpSig.Close, &err)
-
-		blobber, err := blob.Download(uri, tmpSig, &blob.Options{
-			Type:      metadata.Name(),
-			Keyring:   keyring,
-			Encrypted: false,
-		})
-		if err != nil {
-			return nil, "", err
-		}
-
-		tmpZipPath := filepath.Join(tmp, "tmp-zip")
-		err = iofs.Copy(tmpZipPath, blobber)
-		if err != nil {
-			return nil, "", err
-		}
+	err = s.Verify(goOutput, ZipMode)
+	if err != nil {
+		return nil, "", err
+	}
 
-		err = s.Verify(tmpZipPath, ZipMode)
-		if err != nil {
-			return nil, "", err
-		}
+	return signer, s.Checksum, nil
+}
 
-		err = iofs.MoveFile(tmpSigPath, sigOutput)
-		if err != nil {
-			return nil, "", err
-		}
+// Extract the verified zip in ZipPath() to DirPath() the same way as the Go
+// command does it: the zip is unpacked to a temporary directory that's
+// renamed into place, after which every directory is made read-only (the
+// files are created read-only by zip.Unzip()).  The extracted directory is
+// verified both before and after it's moved into place.
+func (s *Source) Extract() (err error) {
+	if !s.verified {
+		return errors.Errorf("%s has not been verified", s.ZipPath())
 	}
 
-	sig, err := os.Open(sigOutput) // #nosec G304
+	exists, err := iofs.Exists(s.DirPath())
 	if err != nil {
-		return nil, "", err
+		return err
+	}
+	if exists {
+		return s.Verify(s.DirPath(), DirMode)
 	}
-	defer errorx.Defer(sig.Close, &err)
 
-	blobber, err := blob.NewReader(sig, &blob.Options{
-		Type:      metadata.Name(),
-		Keyring:   keyring,
-		Encrypted: false,
-	})
+	return s.extract(s.ZipPath())
+}
+
+func (s *Source) extract(zipPath string) (err error) {
+	parent := filepath.Dir(s.DirPath())
+	err = os.MkdirAll(parent, 0700)
 	if err != nil {
-		return nil, "", err
+		return err
 	}
-	s.log.Tracef("%s: signed by: %s", sigOutput, blobber.Signer)
 
-	tmpZipPath := filepath.Join(tmp, "zip")
-	err = iofs.Copy(tmpZipPath, blobber)
+	tmp, err := os.MkdirTemp(parent, filepath.Base(s.DirPath())+".tmp-")
 	if err != nil {
-		return nil, "", err
+		return err
 	}
+	defer errorx.Defer(func() error { return iofs.Remove(tmp) }, &err)
 
-	err = s.Verify(tmpZipPath, ZipMode)
+	// os.MkdirTemp() creates the directory with 0700, whereas Go uses 0777
+	// (minus umask) before it makes the directory read-only.
+	err = os.Chmod(tmp, 0755)
 	if err != nil {
-		return nil, "", err
+		return err
 	}
 
-	zipPathExists, err := iofs.Exists(goOutput)
+	err = zip.Unzip(tmp, module.Version{Path: s.Name, Version: s.Version}, zipPath)
 	if err != nil {
-		return nil, "", err
+		return err
 	}
 
-	if !zipPathExists {
-		err := iofs.MoveFile(tmpZipPath, goOutput)
-		if err != nil {
-			return nil, "", err
-		}
+	err = h1.VerifyDir(tmp, s.Name, s.Version, s.Checksum)
+	if err != nil {
+		return err
 	}
 
-	zipHashPathExists, err := iofs.Exists(goHashOutput)
+	err = os.Rename(tmp, s.DirPath())
 	if err != nil {
-		return nil, "", err
-	}
-	if !zipHashPathExists {
-		err := iofs.WriteFile(goHashOutput, bytes.NewReader([]byte(s.Checksum)))
-		if err != nil {
-			return nil, "", err
-		}
+		return err
 	}
 
-	err = s.Verify(goOutput, ZipMode)
+	err = makeDirsReadOnly(s.DirPath())
 	if err != nil {
-		return nil, "", err
+		return err
 	}
 
-	return blobber.Signer, s.Checksum, nil
+	return s.Verify(s.DirPath(), DirMode)
 }
 
 // Name of the signed codebase.
@@ -253,6 +254,25 @@ func (s *Source) String() string {
 	return fmt.Sprintf("%s@%s", s.Name, s.Version)
 }
 
+func makeDirsReadOnly(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		return os.Chmod(path, info.Mode().Perm()&^0222)
+	})
+}
+
 type CacheResult struct {
 	DirH1 string
 	ModH1 string
@@ -0,0 +1,350 @@
+package mod
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/illikainen/gofer/src/h1"
+
+	"github.com/illikainen/go-cryptor/src/blob"
+	"github.com/illikainen/go-utils/src/errorx"
+	"github.com/illikainen/go-utils/src/iofs"
+	"github.com/illikainen/go-utils/src/seq"
+	"github.com/pkg/errors"
+)
+
+const (
+	FileAdded    = "added"
+	FileRemoved  = "removed"
+	FileModified = "modified"
+)
+
+type FileChange struct {
+	Path    string
+	Kind    string
+	OldSize int64
+	NewSize int64
+	Binary  bool
+	Large   bool
+	Diff    string // unified diff for text files
+}
+
+func (f *FileChange) String() string {
+	kind := "text"
+	if f.Binary {
+		kind = "binary"
+	} else if f.Large {
+		kind = "large"
+	}
+	return fmt.Sprintf("%s: %s %s file (%d => %d bytes)", f.Path, f.Kind, kind, f.OldSize, f.NewSize)
+}
+
+type DiffSourceOptions struct {
+	Include []string // path globs, every file is included if empty
+	Exclude []string // path globs
+	MaxSize int64    // larger files aren't diffed
+	Context int      // lines of context in the unified diff
+}
+
+// Read the module zip into memory.  The zip in GOPATH is used if its h1
+// matches go.sum, and otherwise the zip in the signed blob.  The h1 is
+// computed over the bytes that are returned, so the content can't change
+// after it has been verified.
+func (s *Source) ReadVerified(keyring *blob.Keyring) (z *zip.Reader, err error) {
+	exists, err := iofs.Exists(s.ZipPath())
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		z, err = s.readZip(s.ZipPath())
+		if err == nil {
+			return z, nil
+		}
+		s.log.Warnf("%s: %s", s.ZipPath(), err)
+	}
+
+	tmp, tmpRm, err := iofs.MkdirTemp()
+	if err != nil {
+		return nil, err
+	}
+	defer errorx.Defer(tmpRm, &err)
+
+	zipPath := filepath.Join(tmp, s.ZipName())
+	_, err = fetchSigned(nil, s.SigPath(), zipPath, keyring, s.verifyStream, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.readZip(zipPath)
+}
+
+// Verified module content.  The extracted directory is used if it exists,
+// and otherwise the zip from ReadVerified().  Nil is returned if neither the
+// directory, the zip nor the signed blob exists.
+func (s *Source) VerifiedFS(keyring *blob.Keyring) (fs.FS, error) {
+	dirExists, err := iofs.Exists(s.DirPath())
+	if err != nil {
+		return nil, err
+	}
+
+	if dirExists {
+		err := s.Verify(s.DirPath(), DirMode)
+		if err != nil {
+			return nil, err
+		}
+		return os.DirFS(s.DirPath()), nil
+	}
+
+	zipExists, err := iofs.Exists(s.ZipPath())
+	if err != nil {
+		return nil, err
+	}
+
+	sigExists, err := iofs.Exists(s.SigPath())
+	if err != nil {
+		return nil, err
+	}
+
+	if !zipExists && !sigExists {
+		return nil, nil
+	}
+
+	z, err := s.ReadVerified(keyring)
+	if err != nil {
+		return nil, err
+	}
+
+	return fs.Sub(z, fmt.Sprintf("%s@%s", s.Name, s.Version))
+}
+
+// Directory with the verified module content.  The extracted directory is
+// used if it exists, and otherwise the content of VerifiedFS() is written to
+// a temporary directory that's removed by cleanup.  An empty dir is returned
+// if neither the directory, the zip nor the signed blob exists.
+func (s *Source) VerifiedDir(keyring *blob.Keyring) (dir string, cleanup func() error, err error) {
+	noop := func() error { return nil }
+
+	exists, err := iofs.Exists(s.DirPath())
+	if err != nil {
+		return "", nil, err
+	}
+	if exists {
+		err := s.Verify(s.DirPath(), DirMode)
+		if err != nil {
+			return "", nil, err
+		}
+		return s.DirPath(), noop, nil
+	}
+
+	fsys, err := s.VerifiedFS(keyring)
+	if err != nil {
+		return "", nil, err
+	}
+	if fsys == nil {
+		return "", noop, nil
+	}
+
+	tmp, tmpRm, err := iofs.MkdirTemp()
+	if err != nil {
+		return "", nil, err
+	}
+
+	dir = filepath.Join(tmp, "src")
+	err = writeFS(fsys, dir)
+	if err == nil {
+		err = h1.VerifyDir(dir, s.Name, s.Version, s.Checksum)
+	}
+	if err != nil {
+		return "", nil, errorx.Join(err, tmpRm())
+	}
+
+	return dir, tmpRm, nil
+}
+
+// The paths in fsys have already been validated by h1.HashZip().
+func writeFS(fsys fs.FS, dir string) error {
+	return fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if entry.IsDir() {
+			return os.MkdirAll(path, 0700)
+		}
+		if !entry.Type().IsRegular() {
+			return errors.Errorf("%s: unsupported file type", name)
+		}
+
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path, data, 0600)
+	})
+}
+
+// The zip is read once and both hashed and parsed from the same buffer.
+func (s *Source) readZip(file string) (*zip.Reader, error) {
+	data, err := iofs.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	r := bytes.NewReader(data)
+	cksum, err := h1.HashZipReader(r, r.Size())
+	if err != nil {
+		return nil, errors.Wrap(err, file)
+	}
+	if cksum != s.Checksum {
+		return nil, errors.Errorf("%s: bad checksum: %s != %s", file, cksum, s.Checksum)
+	}
+
+	return zip.NewReader(r, r.Size())
+}
+
+// Compare the files in two module zips.  The module prefix is stripped
+// from every path.
+func DiffSource(oldZip *zip.Reader, newZip *zip.Reader, opts *DiffSourceOptions) ([]*FileChange, error) {
+	oldFiles := zipFiles(oldZip)
+	newFiles := zipFiles(newZip)
+
+	paths := []string{}
+	for name := range oldFiles {
+		paths = append(paths, name)
+	}
+	for name := range newFiles {
+		paths = append(paths, name)
+	}
+	paths = seq.Uniq(paths)
+	sort.Strings(paths)
+
+	changes := []*FileChange{}
+	for _, name := range paths {
+		if !matchGlobs(name, opts.Include, true) || matchGlobs(name, opts.Exclude, false) {
+			continue
+		}
+
+		oldData, err := readZipFile(oldFiles[name], opts.MaxSize)
+		if err != nil {
+			return nil, err
+		}
+
+		newData, err := readZipFile(newFiles[name], opts.MaxSize)
+		if err != nil {
+			return nil, err
+		}
+
+		change := &FileChange{Path: name, Kind: FileModified}
+		switch {
+		case oldFiles[name] == nil:
+			change.Kind = FileAdded
+		case newFiles[name] == nil:
+			change.Kind = FileRemoved
+		}
+
+		if change.Kind == FileModified && oldFiles[name].CRC32 == newFiles[name].CRC32 &&
+			oldFiles[name].UncompressedSize64 == newFiles[name].UncompressedSize64 &&
+			bytes.Equal(oldData, newData) {
+			continue
+		}
+
+		if oldFiles[name] != nil {
+			change.OldSize = int64(oldFiles[name].UncompressedSize64)
+		}
+		if newFiles[name] != nil {
+			change.NewSize = int64(newFiles[name].UncompressedSize64)
+		}
+
+		switch {
+		case change.OldSize > opts.MaxSize || change.NewSize > opts.MaxSize:
+			change.Large = true
+		case isBinary(oldData) || isBinary(newData):
+			change.Binary = true
+		default:
+			oldName := fmt.Sprintf("a/%s", name)
+			newName := fmt.Sprintf("b/%s", name)
+			if change.Kind == FileAdded {
+				oldName = "/dev/null"
+			} else if change.Kind == FileRemoved {
+				newName = "/dev/null"
+			}
+			change.Diff = unifiedDiff(oldName, newName, string(oldData), string(newData), opts.Context)
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+func zipFiles(z *zip.Reader) map[string]*zip.File {
+	files := map[string]*zip.File{}
+	for _, f := range z.File {
+		if strings.HasSuffix(f.Name, "/") {
+			continue
+		}
+
+		// Every file in a module zip is prefixed with module@version/.
+		_, name, ok := strings.Cut(f.Name, "@")
+		if ok {
+			_, name, ok = strings.Cut(name, "/")
+		}
+		if !ok {
+			name = f.Name
+		}
+		files[name] = f
+	}
+	return files
+}
+
+func readZipFile(f *zip.File, maxSize int64) (data []byte, err error) {
+	if f == nil || int64(f.UncompressedSize64) > maxSize {
+		return nil, nil
+	}
+
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer errorx.Defer(r.Close, &err)
+
+	return io.ReadAll(io.LimitReader(r, maxSize+1))
+}
+
+func matchGlobs(name string, globs []string, empty bool) bool {
+	if len(globs) == 0 {
+		return empty
+	}
+
+	return seq.ContainsBy(globs, func(glob string) bool {
+		if ok, _ := path.Match(glob, name); ok {
+			return true
+		}
+
+		// Globs without a separator match the base name, and globs that
+		// end with a separator match a directory prefix.
+		if !strings.Contains(glob, "/") {
+			ok, _ := path.Match(glob, path.Base(name))
+			return ok
+		}
+		return strings.HasSuffix(glob, "/") && strings.HasPrefix(name, glob)
+	})
+}
+
+func isBinary(data []byte) bool {
+	sample := data
+	if len(sample) > 8000 {
+		sample = sample[:8000]
+	}
+	return bytes.IndexByte(sample, 0) >= 0 || !utf8.Valid(data)
+}
@@ -0,0 +1,243 @@
+package mod
+
+import (
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/illikainen/gofer/src/metadata"
+
+	"github.com/illikainen/go-cryptor/src/blob"
+	"github.com/illikainen/go-cryptor/src/cryptor"
+	"github.com/illikainen/go-utils/src/errorx"
+	"github.com/illikainen/go-utils/src/fn"
+	"github.com/illikainen/go-utils/src/iofs"
+	"github.com/pkg/errors"
+)
+
+// Files are staged in the same directory as their final destination so that
+// they can be moved into place with an atomic rename once their content has
+// been verified.  Closing a staged file that hasn't been committed removes
+// it.
+type stagedFile struct {
+	*os.File
+	dst       string
+	closed    bool
+	committed bool
+}
+
+func stageFile(dst string) (*stagedFile, error) {
+	dir := filepath.Dir(dst)
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.CreateTemp(dir, "."+filepath.Base(dst)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+
+	return &stagedFile{File: f, dst: dst}, nil
+}
+
+func (s *stagedFile) Commit() error {
+	if s.closed {
+		return errors.Errorf("%s: already closed", s.Name())
+	}
+
+	err := s.Sync()
+	if err != nil {
+		return err
+	}
+
+	s.closed = true
+	err = s.File.Close()
+	if err != nil {
+		return errorx.Join(err, os.Remove(s.Name()))
+	}
+
+	err = os.Rename(s.Name(), s.dst)
+	if err != nil {
+		return errorx.Join(err, os.Remove(s.Name()))
+	}
+
+	s.committed = true
+	return nil
+}
+
+func (s *stagedFile) Close() error {
+	if s.committed {
+		return nil
+	}
+
+	var err error
+	if !s.closed {
+		s.closed = true
+		err = s.File.Close()
+	}
+
+	return errorx.Join(err, iofs.Remove(s.Name()))
+}
+
+// A streamVerifier consumes the payload of a signed blob from r.  Everything
+// read from r is also written to staged, which may be used for random access
+// once r has been drained.
+type streamVerifier func(r io.Reader, staged *os.File) error
+
+// Retrieve the signed blob for sigOutput (downloading it from uri if it
+// doesn't exist) and verify its payload with verify while it's being read.
+// The blob and the payload are only moved into sigOutput and goOutput once
+// both the signature and the content have been verified.  An existing
+// goOutput is left as-is unless replace is set; it's up to the caller to
+// verify it.  If uri is nil, sigOutput must already exist.
+func fetchSigned(uri *url.URL, sigOutput string, goOutput string, keyring *blob.Keyring,
+	verify streamVerifier, replace bool) (signer cryptor.PublicKey, err error) {
+	opts := &blob.Options{
+		Type:      metadata.Name(),
+		Keyring:   keyring,
+		Encrypted: false,
+	}
+
+	sigPathExists, err := iofs.Exists(sigOutput)
+	if err != nil {
+		return nil, err
+	}
+
+	var sig *stagedFile
+	var blobber *blob.Reader
+	if sigPathExists {
+		f, err := os.Open(sigOutput) // #nosec G304
+		if err != nil {
+			return nil, err
+		}
+		defer errorx.Defer(f.Close, &err)
+
+		blobber, err = blob.NewReader(f, opts)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if uri == nil {
+			return nil, errors.Errorf("%s: no signed copy available", sigOutput)
+		}
+
+		sig, err = stageFile(sigOutput)
+		if err != nil {
+			return nil, err
+		}
+		defer errorx.Defer(sig.Close, &err)
+
+		blobber, err = blob.Download(uri, sig.File, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	payload, err := stageFile(goOutput)
+	if err != nil {
+		return nil, err
+	}
+	defer errorx.Defer(payload.Close, &err)
+
+	tee := io.TeeReader(&payloadReader{blobber}, payload.File)
+	err = verify(tee, payload.File)
+	if err != nil {
+		return nil, errors.Wrap(err, sigOutput)
+	}
+
+	n, err := io.Copy(io.Discard, tee)
+	if err != nil {
+		return nil, err
+	}
+	if n != 0 {
+		return nil, errors.Errorf("%s: trailing data", sigOutput)
+	}
+
+	if sig != nil {
+		err = sig.Commit()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	goPathExists, err := iofs.Exists(goOutput)
+	if err != nil {
+		return nil, err
+	}
+	if !goPathExists || replace {
+		err = payload.Commit()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return blobber.Signer, nil
+}
+
+// The blob reader returns -1 on errors, which is rejected by some of the io
+// helpers that are used to consume the payload.
+type payloadReader struct {
+	r io.Reader
+}
+
+func (p *payloadReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n < 0 {
+		return 0, fn.Ternary(err != nil, err, errors.Errorf("invalid read"))
+	}
+	return n, err
+}
+
+// Read the payload of a signed blob.
+func readSigned(file string, keyring *blob.Keyring) (data []byte, err error) {
+	f, err := os.Open(file) // #nosec G304
+	if err != nil {
+		return nil, err
+	}
+	defer errorx.Defer(f.Close, &err)
+
+	blobber, err := blob.NewReader(f, &blob.Options{
+		Type:      metadata.Name(),
+		Keyring:   keyring,
+		Encrypted: false,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(&payloadReader{blobber})
+}
+
+// Atomically write data to dst unless it already exists.
+func writeIfNotExists(dst string, data []byte) error {
+	exists, err := iofs.Exists(dst)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	return writeFile(dst, data)
+}
+
+// Atomically replace dst with data.
+func writeFile(dst string, data []byte) (err error) {
+	f, err := stageFile(dst)
+	if err != nil {
+		return err
+	}
+	defer errorx.Defer(f.Close, &err)
+
+	n, err := f.Write(data)
+	if err != nil {
+		return err
+	}
+	if n != len(data) {
+		return iofs.ErrInvalidSize
+	}
+
+	return f.Commit()
+}
@@ -0,0 +1,359 @@
+package mod
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/illikainen/go-utils/src/errorx"
+	"github.com/illikainen/go-utils/src/fn"
+	"github.com/illikainen/go-utils/src/iofs"
+	"github.com/illikainen/go-utils/src/logging"
+	"github.com/illikainen/go-utils/src/seq"
+	"github.com/pkg/errors"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/sumdb"
+	"golang.org/x/mod/sumdb/note"
+	"golang.org/x/sync/errgroup"
+)
+
+// Verifier keys of well-known checksum databases, the same as GOSUMDB
+// accepts them.
+var knownSumDBs = map[string]string{
+	"sum.golang.org": "sum.golang.org+033de0ae+Ac4zctda0e5eza+HJyk9SxEdh+s3Ly+H3bJBBXXvP9s",
+}
+
+// Largest response that's read from a checksum database.  Tiles are at most
+// 8 KiB and lookups are a few hundred bytes.
+const maxSumDBResponse = 1 << 20
+
+type SumDBOptions struct {
+	Key       string       // verifier key or the name of a well-known database
+	URL       string       // e.g. https://proxy.golang.org/sumdb/sum.golang.org, offline if empty
+	Client    *http.Client // required unless offline
+	CacheDir  string       // lookups and tiles, e.g. $GOPATH/pkg/mod/cache/download/sumdb
+	ConfigDir string       // latest signed tree head, e.g. $GOPATH/pkg/sumdb, kept in memory if empty
+	Log       logging.Logger
+}
+
+// SumDB looks up go.sum lines in a checksum database.  Every lookup is
+// authenticated with the signed tree head and tile proofs, and the tree
+// head is persisted so that a database that rewrites its history is
+// detected.  The cache and configuration directories use the same layout
+// as the Go command.
+type SumDB struct {
+	Name   string
+	client *sumdb.Client
+	ops    *sumdbOps
+}
+
+var (
+	errNotInSumDB    = errors.New("not in the checksum database")
+	errSumDBMismatch = errors.New("checksum mismatch")
+)
+
+func NewSumDB(opts *SumDBOptions) (*SumDB, error) {
+	key, name, err := parseSumDBKey(opts.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	ops := &sumdbOps{
+		key:       key,
+		name:      name,
+		client:    opts.Client,
+		cacheDir:  opts.CacheDir,
+		configDir: opts.ConfigDir,
+		log:       fn.Ternary(opts.Log != nil, opts.Log, logging.DiscardLogger()),
+	}
+
+	if opts.URL != "" {
+		ops.url, err = url.Parse(strings.TrimSuffix(opts.URL, "/"))
+		if err != nil {
+			return nil, err
+		}
+		if ops.client == nil {
+			return nil, errors.Errorf("%s: no HTTP client", ops.name)
+		}
+	}
+
+	return &SumDB{Name: ops.name, client: sumdb.NewClient(ops), ops: ops}, nil
+}
+
+// Name of the checksum database with a verifier key or a well-known name.
+func SumDBName(key string) (string, error) {
+	_, name, err := parseSumDBKey(key)
+	return name, err
+}
+
+func parseSumDBKey(key string) (string, string, error) {
+	known, ok := knownSumDBs[key]
+	if ok {
+		key = known
+	}
+
+	verifier, err := note.NewVerifier(key)
+	if err != nil {
+		return "", "", errors.Errorf("invalid checksum database key: %s", key)
+	}
+	return key, verifier.Name(), nil
+}
+
+// Check that the checksum database has the same checksum for a module
+// version (or its .mod file) as a go.sum file.  errNotInSumDB is returned
+// if the database doesn't have the module version and errSumDBMismatch if
+// it has another checksum.  Other errors mean that the lookup failed, e.g.
+// because of the network or a misbehaving database.
+func (d *SumDB) Check(name string, version string, mod bool, checksum string) error {
+	lookup := version
+	if mod {
+		lookup += "/go.mod"
+	}
+
+	lines, err := d.client.Lookup(name, lookup)
+	if err != nil {
+		if d.ops.notFound(name, version) {
+			return errors.Wrapf(errNotInSumDB, "%s: %s@%s", d.Name, name, lookup)
+		}
+		return errors.Errorf("%s: %s", d.Name, err)
+	}
+
+	if !seq.Contains(lines, fmt.Sprintf("%s %s %s", name, lookup, checksum)) {
+		return errors.Wrapf(errSumDBMismatch, "%s: %s@%s: %s", d.Name, name, lookup, checksum)
+	}
+	return nil
+}
+
+// Check every line in the go.sum file(s) against the checksum database.
+// Lines that the database doesn't contain or contradicts are logged and
+// returned.  Lookups that fail for other reasons are errors since nothing
+// is known about the line.
+func (s *SumFile) CheckSumDB(db *SumDB) ([]string, error) {
+	type line struct {
+		name     string
+		version  string
+		mod      bool
+		checksum string
+	}
+
+	lines := []*line{}
+	for _, src := range s.Sources {
+		lines = append(lines, &line{src.Name, src.Version, false, src.Checksum})
+	}
+	for _, m := range s.ModFiles {
+		lines = append(lines, &line{m.Name, m.Version, true, m.Checksum})
+	}
+
+	group := errgroup.Group{}
+	semaphore := make(chan int, 3)
+	mu := sync.Mutex{}
+	failed := []string{}
+
+	for _, l := range lines {
+		l := l
+		group.Go(func() error {
+			semaphore <- 1
+			defer func() { <-semaphore }()
+
+			err := db.Check(l.name, l.version, l.mod, l.checksum)
+			if err != nil && !errors.Is(err, errNotInSumDB) && !errors.Is(err, errSumDBMismatch) {
+				return err
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			text := fmt.Sprintf("%s %s%s %s", l.name, l.version, fn.Ternary(l.mod, "/go.mod", ""), l.checksum)
+			if err != nil {
+				s.log.Errorf("%s", err)
+				failed = append(failed, text)
+			} else {
+				s.log.Debugf("%s: verified %s", db.Name, text)
+			}
+			return nil
+		})
+	}
+
+	err := group.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	failed = seq.Uniq(failed)
+	sort.Strings(failed)
+	return failed, nil
+}
+
+type sumdbOps struct {
+	key       string
+	name      string
+	url       *url.URL
+	client    *http.Client
+	cacheDir  string
+	configDir string
+	log       logging.Logger
+	mu        sync.Mutex
+	latest    []byte   // used if there's no configDir
+	missing   []string // lookups that the database doesn't have
+}
+
+func (o *sumdbOps) ReadRemote(path string) (data []byte, err error) {
+	if o.url == nil {
+		// The log can't be searched without the lookup, so a module
+		// version that isn't in the snapshot is reported as missing.
+		if strings.HasPrefix(path, "/lookup/") {
+			o.mu.Lock()
+			o.missing = append(o.missing, path)
+			o.mu.Unlock()
+		}
+		return nil, errors.Errorf("%s%s: not available offline", o.name, path)
+	}
+
+	uri := o.url.String() + path
+	o.log.Debugf("%s: download %s", o.name, uri)
+
+	resp, err := o.client.Get(uri)
+	if err != nil {
+		return nil, err
+	}
+	defer errorx.Defer(resp.Body.Close, &err)
+
+	if strings.HasPrefix(path, "/lookup/") &&
+		(resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone) {
+		o.mu.Lock()
+		o.missing = append(o.missing, path)
+		o.mu.Unlock()
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("%s: %s", uri, resp.Status)
+	}
+
+	data, err = io.ReadAll(io.LimitReader(resp.Body, maxSumDBResponse+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxSumDBResponse {
+		return nil, errors.Errorf("%s: response too large", uri)
+	}
+	return data, nil
+}
+
+// Whether a lookup of a module version has been answered with a 404 or
+// 410, i.e. that the database doesn't have it, or isn't in the snapshot
+// when offline.
+func (o *sumdbOps) notFound(name string, version string) bool {
+	escName, err := module.EscapePath(name)
+	if err != nil {
+		return false
+	}
+
+	escVersion, err := module.EscapeVersion(version)
+	if err != nil {
+		return false
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return seq.Contains(o.missing, "/lookup/"+escName+"@"+escVersion)
+}
+
+func (o *sumdbOps) ReadConfig(file string) ([]byte, error) {
+	if file == "key" {
+		return []byte(o.key), nil
+	}
+
+	path, err := o.configPath(file)
+	if err != nil {
+		return nil, err
+	}
+	if o.configDir == "" {
+		return o.latest, nil
+	}
+
+	data, err := iofs.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return []byte{}, nil
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+func (o *sumdbOps) WriteConfig(file string, old []byte, next []byte) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	cur, err := o.ReadConfig(file)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(cur, old) {
+		return sumdb.ErrWriteConflict
+	}
+
+	path, err := o.configPath(file)
+	if err != nil {
+		return err
+	}
+	if o.configDir == "" {
+		o.latest = next
+		return nil
+	}
+	return writeFile(path, next)
+}
+
+func (o *sumdbOps) ReadCache(file string) ([]byte, error) {
+	path, err := safeJoin(o.cacheDir, file)
+	if err != nil {
+		return nil, err
+	}
+	return iofs.ReadFile(path)
+}
+
+// Tiles and lookups are only written once they've been authenticated.
+// Failures aren't fatal since they can be fetched again.
+func (o *sumdbOps) WriteCache(file string, data []byte) {
+	path, err := safeJoin(o.cacheDir, file)
+	if err == nil {
+		err = writeFile(path, data)
+	}
+	if err != nil {
+		o.log.Debugf("%s: unable to cache %s: %s", o.name, file, err)
+	}
+}
+
+func (o *sumdbOps) Log(msg string) {
+	o.log.Debugf("%s: %s", o.name, msg)
+}
+
+func (o *sumdbOps) SecurityError(msg string) {
+	o.log.Errorf("%s: %s", o.name, msg)
+}
+
+// Only the latest signed tree head is stored as configuration.
+func (o *sumdbOps) configPath(file string) (string, error) {
+	if file != o.name+"/latest" {
+		return "", errors.Errorf("%s: invalid configuration file: %s", o.name, file)
+	}
+	if o.configDir == "" {
+		return "", nil
+	}
+	return safeJoin(o.configDir, file)
+}
+
+// Join a slash-separated path that's generated from module paths and
+// versions to dir, refusing paths that would escape it.
+func safeJoin(dir string, file string) (string, error) {
+	path := filepath.Join(dir, filepath.FromSlash(file))
+	if dir == "" || !strings.HasPrefix(path, filepath.Clean(dir)+string(filepath.Separator)) {
+		return "", errors.Errorf("invalid path: %s", file)
+	}
+	return path, nil
+}
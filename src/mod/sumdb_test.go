@@ -0,0 +1,107 @@
+package mod
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCheckSumDB(t *testing.T) {
+	m := newTestModule(t, "example.com/m", "v1.0.0", "")
+	other := newTestModule(t, "example.com/m", "v1.0.0", "var X = 1\n")
+	missing := newTestModule(t, "example.com/missing", "v1.0.0", "")
+
+	sum, err := ReadGoSum(&SumOptions{SumData: [][]byte{m.GoSum(), missing.GoSum()}, SigPath: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The .zip line of m is contradicted, the .mod file is the same.
+	handler, vkey := newTestSumDBHandler(t, "sum.example.com", http.NotFoundHandler(), other)
+	proxy := newTestProxy(t, handler)
+	failed, err := sum.CheckSumDB(newTestSumDB(t, proxy, "sum.example.com", vkey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"example.com/m v1.0.0 " + m.ZipSum,
+		"example.com/missing v1.0.0 " + missing.ZipSum,
+		"example.com/missing v1.0.0/go.mod " + missing.ModSum,
+	}
+	if strings.Join(failed, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got %q, want %q", failed, want)
+	}
+}
+
+func TestCheckSumDBLookupError(t *testing.T) {
+	m := newTestModule(t, "example.com/m", "v1.0.0", "")
+	sum, err := ReadGoSum(&SumOptions{SumData: [][]byte{m.GoSum()}, SigPath: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler, vkey := newTestSumDBHandler(t, "sum.example.com", http.NotFoundHandler(), m)
+	proxy := newTestProxy(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/lookup/") {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+
+	failed, err := sum.CheckSumDB(newTestSumDB(t, proxy, "sum.example.com", vkey))
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Fatalf("expected a lookup error, got %q, %v", failed, err)
+	}
+}
+
+func TestCheckSumDBOffline(t *testing.T) {
+	m := newTestModule(t, "example.com/m", "v1.0.0", "")
+	missing := newTestModule(t, "example.com/missing", "v1.0.0", "")
+	snapshot := t.TempDir()
+
+	// Export the lookups and tiles of m into the snapshot.
+	handler, vkey := newTestSumDBHandler(t, "sum.example.com", http.NotFoundHandler(), m, missing)
+	proxy := newTestProxy(t, handler)
+	online, err := NewSumDB(&SumDBOptions{
+		Key:      vkey,
+		URL:      proxy.SumDBURL("sum.example.com"),
+		Client:   proxy.Client,
+		CacheDir: snapshot,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sum, err := ReadGoSum(&SumOptions{SumData: [][]byte{m.GoSum()}, SigPath: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed, err := sum.CheckSumDB(online)
+	if err != nil || len(failed) != 0 {
+		t.Fatalf("unexpected result: %q, %v", failed, err)
+	}
+
+	offline, err := NewSumDB(&SumDBOptions{Key: vkey, CacheDir: snapshot})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sum, err = ReadGoSum(&SumOptions{SumData: [][]byte{m.GoSum(), missing.GoSum()}, SigPath: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed, err = sum.CheckSumDB(offline)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"example.com/missing v1.0.0 " + missing.ZipSum,
+		"example.com/missing v1.0.0/go.mod " + missing.ModSum,
+	}
+	if strings.Join(failed, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got %q, want %q", failed, want)
+	}
+}
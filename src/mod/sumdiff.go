@@ -0,0 +1,155 @@
+package mod
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/illikainen/go-utils/src/fn"
+	"github.com/illikainen/go-utils/src/iofs"
+	"github.com/illikainen/go-utils/src/seq"
+	"golang.org/x/mod/semver"
+)
+
+const (
+	DiffAdded      = "added"
+	DiffRemoved    = "removed"
+	DiffUpgraded   = "upgraded"
+	DiffDowngraded = "downgraded"
+	DiffChecksum   = "checksum changed"
+)
+
+type SumChange struct {
+	Name     string
+	Kind     string
+	Old      string   // highest old version, or the version with a changed checksum
+	New      string   // highest new version
+	Detail   string   `json:",omitempty"`
+	Unsigned []string `json:",omitempty"` // new files without a signed blob
+}
+
+// Suspicious changes shouldn't happen unless a module version was
+// republished or go.sum was tampered with.
+func (c *SumChange) Suspicious() bool {
+	return c.Kind == DiffChecksum
+}
+
+func (c *SumChange) String() string {
+	switch c.Kind {
+	case DiffAdded:
+		return fmt.Sprintf("%s: %s %s", c.Name, c.Kind, c.New)
+	case DiffRemoved:
+		return fmt.Sprintf("%s: %s %s", c.Name, c.Kind, c.Old)
+	case DiffChecksum:
+		return fmt.Sprintf("%s@%s: %s (%s)", c.Name, c.Old, c.Kind, c.Detail)
+	}
+	return fmt.Sprintf("%s: %s %s => %s", c.Name, c.Kind, c.Old, c.New)
+}
+
+type sumVersion struct {
+	version  string
+	gomod    bool
+	checksum string
+	sigPath  string
+}
+
+// Compare the modules in two go.sum file sets.  Modules are compared by
+// their highest version since go.sum also lists versions that are only
+// needed for the module graph.  The signature directory of s is consulted to
+// determine whether new versions have already been signed.
+func (s *SumFile) Diff(old *SumFile) ([]*SumChange, error) {
+	oldMods := old.versions()
+	newMods := s.versions()
+
+	names := []string{}
+	for name := range oldMods {
+		names = append(names, name)
+	}
+	for name := range newMods {
+		names = append(names, name)
+	}
+	names = seq.Uniq(names)
+	sort.Strings(names)
+
+	changes := []*SumChange{}
+	for _, name := range names {
+		change := &SumChange{
+			Name: name,
+			Old:  highestVersion(oldMods[name]),
+			New:  highestVersion(newMods[name]),
+		}
+
+		switch {
+		case change.Old == "":
+			change.Kind = DiffAdded
+		case change.New == "":
+			change.Kind = DiffRemoved
+		case semver.Compare(change.New, change.Old) > 0:
+			change.Kind = DiffUpgraded
+		case semver.Compare(change.New, change.Old) < 0:
+			change.Kind = DiffDowngraded
+		}
+
+		for _, nv := range newMods[name] {
+			ov, ok := seq.FindBy(oldMods[name], func(ov *sumVersion) bool {
+				return ov.version == nv.version && ov.gomod == nv.gomod
+			})
+			if ok {
+				if ov.checksum != nv.checksum {
+					suffix := fn.Ternary(nv.gomod, "/go.mod", "")
+					changes = append(changes, &SumChange{
+						Name:   name,
+						Kind:   DiffChecksum,
+						Old:    nv.version + suffix,
+						New:    nv.version + suffix,
+						Detail: fmt.Sprintf("%s => %s", ov.checksum, nv.checksum),
+					})
+				}
+				continue
+			}
+
+			signed, err := iofs.Exists(nv.sigPath)
+			if err != nil {
+				return nil, err
+			}
+			if !signed {
+				change.Unsigned = append(change.Unsigned, nv.version+fn.Ternary(nv.gomod, ".mod", ".zip"))
+			}
+		}
+
+		if change.Kind != "" {
+			changes = append(changes, change)
+		}
+	}
+
+	return changes, nil
+}
+
+func (s *SumFile) versions() map[string][]*sumVersion {
+	mods := map[string][]*sumVersion{}
+	for _, src := range s.Sources {
+		mods[src.Name] = append(mods[src.Name], &sumVersion{
+			version:  src.Version,
+			checksum: src.Checksum,
+			sigPath:  src.SigPath(),
+		})
+	}
+	for _, m := range s.ModFiles {
+		mods[m.Name] = append(mods[m.Name], &sumVersion{
+			version:  m.Version,
+			gomod:    true,
+			checksum: m.Checksum,
+			sigPath:  m.SigPath(),
+		})
+	}
+	return mods
+}
+
+func highestVersion(versions []*sumVersion) string {
+	highest := ""
+	for _, v := range versions {
+		if highest == "" || semver.Compare(v.version, highest) > 0 {
+			highest = v.version
+		}
+	}
+	return highest
+}
@@ -21,13 +21,17 @@ import (
 	"github.com/illikainen/go-utils/src/logging"
 	"github.com/illikainen/go-utils/src/seq"
 	"github.com/pkg/errors"
+	"golang.org/x/mod/module"
 	"golang.org/x/sync/errgroup"
 )
 
 type SumOptions struct {
 	SumFiles []string
+	SumData  [][]byte // go.sum content that isn't read from a file, e.g. from git
 	SigPath  string
 	GoPath   string
+	Local    []module.Version  // trusted local modules, see ReadWorkspace()
+	Origins  map[string]string // see expectedOrigin()
 	Log      logging.Logger
 }
 
@@ -36,6 +40,7 @@ type SumFile struct {
 	ModFiles []*ModFile
 	sigPath  string
 	goPath   string
+	origins  map[string]string
 	log      logging.Logger
 }
 
@@ -43,6 +48,7 @@ func ReadGoSum(opts *SumOptions) (g *SumFile, err error) {
 	gosum := &SumFile{
 		sigPath: opts.SigPath,
 		goPath:  opts.GoPath,
+		origins: opts.Origins,
 		log:     fn.Ternary(opts.Log != nil, opts.Log, logging.DiscardLogger()),
 	}
 	seen := []string{}
@@ -53,65 +59,82 @@ func ReadGoSum(opts *SumOptions) (g *SumFile, err error) {
 			return nil, err
 		}
 
-		scan := bufio.NewScanner(bytes.NewReader(data))
-		for scan.Scan() {
-			err := scan.Err()
-			if err != nil {
-				return nil, err
-			}
+		err = gosum.parse(data, opts, &seen)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-			elts := strings.Split(scan.Text(), " ")
-			if len(elts) != 3 {
-				return nil, errors.Errorf("invalid line: %s", scan.Text())
-			}
+	for _, data := range opts.SumData {
+		err := gosum.parse(data, opts, &seen)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-			name, err := validateName(elts[0])
-			if err != nil {
-				return nil, err
-			}
+	return gosum, nil
+}
 
-			version, mod, err := validateVersion(elts[1])
-			if err != nil {
-				return nil, err
-			}
+func (s *SumFile) parse(data []byte, opts *SumOptions, seen *[]string) error {
+	scan := bufio.NewScanner(bytes.NewReader(data))
+	for scan.Scan() {
+		err := scan.Err()
+		if err != nil {
+			return err
+		}
 
-			cksum, err := validateChecksum(elts[2])
-			if err != nil {
-				return nil, err
-			}
+		elts := strings.Split(scan.Text(), " ")
+		if len(elts) != 3 {
+			return errors.Errorf("invalid line: %s", scan.Text())
+		}
 
-			seenElt := fmt.Sprintf("%s@%s@%s", name, version, cksum)
-			if !seq.Contains(seen, seenElt) {
-				if mod {
-					gosum.ModFiles = append(gosum.ModFiles, &ModFile{
-						Name:     name,
-						Version:  version,
-						Checksum: cksum,
-						GoPath:   opts.GoPath,
-						sigPath:  opts.SigPath,
-						log:      gosum.log,
-					})
-				} else {
-					gosum.Sources = append(gosum.Sources, &Source{
-						Name:     name,
-						Version:  version,
-						Checksum: cksum,
-						GoPath:   opts.GoPath,
-						sigPath:  opts.SigPath,
-						log:      gosum.log,
-					})
-				}
-				seen = append(seen, seenElt)
-			}
+		name, err := validateName(elts[0])
+		if err != nil {
+			return err
 		}
 
-		err = scan.Err()
+		version, mod, err := validateVersion(elts[1])
 		if err != nil {
-			return nil, err
+			return err
+		}
+
+		cksum, err := validateChecksum(elts[2])
+		if err != nil {
+			return err
+		}
+
+		if isLocal(opts.Local, name, version) {
+			s.log.Debugf("%s@%s: provided by a local directory", name, version)
+			continue
+		}
+
+		seenElt := fmt.Sprintf("%s@%s@%s", name, version, cksum)
+		if !seq.Contains(*seen, seenElt) {
+			if mod {
+				s.ModFiles = append(s.ModFiles, &ModFile{
+					Name:     name,
+					Version:  version,
+					Checksum: cksum,
+					GoPath:   opts.GoPath,
+					sigPath:  opts.SigPath,
+					origins:  opts.Origins,
+					log:      s.log,
+				})
+			} else {
+				s.Sources = append(s.Sources, &Source{
+					Name:     name,
+					Version:  version,
+					Checksum: cksum,
+					GoPath:   opts.GoPath,
+					sigPath:  opts.SigPath,
+					log:      s.log,
+				})
+			}
+			*seen = append(*seen, seenElt)
 		}
 	}
 
-	return gosum, nil
+	return scan.Err()
 }
 
 type VerifyResult struct {
@@ -119,6 +142,7 @@ type VerifyResult struct {
 	SignedSources   []string
 	SignedModFiles  []string
 	SignedInfoFiles []string
+	SignedReviews   []string
 	GoZipSources    []string
 	GoDirSources    []string
 	GoModFiles      []string
@@ -214,6 +238,18 @@ func (s *SumFile) Verify(keyring *blob.Keyring) (vr *VerifyResult, err error) {
 			}
 		}
 
+		for _, src := range s.Sources {
+			if src.ReviewName() == elt.Name() && !seq.Contains(vr.SignedReviews, elt.Name()) {
+				review, err := src.readReview(blobber)
+				if err != nil {
+					return nil, err
+				}
+
+				s.log.Infof("%-*s: %s by %s", align, elt.Name(), review.Verdict, review.Reviewer)
+				vr.SignedReviews = append(vr.SignedReviews, elt.Name())
+			}
+		}
+
 		for _, m := range s.ModFiles {
 			if m.SigName() == elt.Name() && !seq.Contains(vr.SignedModFiles, elt.Name()) {
 				tmpfile := filepath.Join(tmp, m.ModName())
@@ -388,7 +424,32 @@ func (s *SumFile) VerifyAndSign(keyring *blob.Keyring) error {
 	return nil
 }
 
-func (s *SumFile) DownloadAndVerify(uri string, keyring *blob.Keyring) error {
+func (s *SumFile) newModFile(name string, version string) *ModFile {
+	return &ModFile{
+		Name:    name,
+		Version: version,
+		GoPath:  s.goPath,
+		sigPath: s.sigPath,
+		origins: s.origins,
+		log:     s.log,
+	}
+}
+
+func (s *SumFile) newInfoFile(name string, version string) *InfoFile {
+	return &InfoFile{
+		Name:    name,
+		Version: version,
+		GoPath:  s.goPath,
+		sigPath: s.sigPath,
+		origins: s.origins,
+		log:     s.log,
+	}
+}
+
+// Download and verify signed modules and metadata.  If a cooldown policy is
+// given, it's checked once the metadata has been retrieved so that refused
+// module code never reaches GOPATH.
+func (s *SumFile) DownloadAndVerify(uri string, keyring *blob.Keyring, cooldown *Cooldown) error {
 	group := errgroup.Group{}
 	semaphore := make(chan int, 3)
 
@@ -457,6 +518,18 @@ func (s *SumFile) DownloadAndVerify(uri string, keyring *blob.Keyring) error {
 		}
 	}
 
+	err = group.Wait()
+	if err != nil {
+		return err
+	}
+
+	if cooldown != nil {
+		err = cooldown.Check(s)
+		if err != nil {
+			return err
+		}
+	}
+
 	for _, src := range s.Sources {
 		u, err := baseuri.Parse(filepath.Join(baseuri.Path, src.SigName()))
 		if err != nil {
@@ -480,3 +553,38 @@ func (s *SumFile) DownloadAndVerify(uri string, keyring *blob.Keyring) error {
 
 	return group.Wait()
 }
+
+// Extract every verified zip to the directory that Go uses for module code.
+func (s *SumFile) Extract() error {
+	align := 0
+	aligner := seq.MaxBy(s.Sources, func(a *Source, b *Source) bool {
+		return len(a.String()) > len(b.String())
+	})
+	if aligner != nil {
+		align = len(aligner.String())
+	}
+
+	for _, src := range s.Sources {
+		exists, err := iofs.Exists(src.ZipPath())
+		if err != nil {
+			return err
+		}
+		if !exists {
+			s.log.Debugf("%-*s: not available", align, src)
+			continue
+		}
+
+		err = src.Verify(src.ZipPath(), ZipMode)
+		if err != nil {
+			return err
+		}
+
+		err = src.Extract()
+		if err != nil {
+			return err
+		}
+		s.log.Infof("%-*s: extracted %s", align, src, src.Checksum)
+	}
+
+	return nil
+}
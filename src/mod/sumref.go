@@ -0,0 +1,99 @@
+package mod
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/illikainen/gofer/src/git"
+
+	"github.com/illikainen/go-utils/src/iofs"
+	"github.com/illikainen/go-utils/src/seq"
+	"github.com/pkg/errors"
+)
+
+// Read go.sum content from a file or from a git revision in the form <rev>
+// or <rev>:<path>.  Revisions are resolved relative to the go.sum files
+// that are being checked: <path> is relative to the directory of the first
+// file in sumFiles, and if it's omitted, every file in sumFiles is read at
+// the revision.  Files that don't exist at the revision are skipped, e.g.
+// the go.sum of a module that has been added to a workspace since.  The
+// other options are taken from opts.
+func ReadSumRef(ref string, sumFiles []string, opts *SumOptions) (*SumFile, error) {
+	refOpts := *opts
+	refOpts.SumFiles = nil
+	refOpts.SumData = nil
+
+	exists, err := iofs.Exists(ref)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		refOpts.SumFiles = []string{ref}
+		return ReadGoSum(&refOpts)
+	}
+
+	if len(sumFiles) == 0 {
+		return nil, errors.Errorf("%s: no go.sum file to resolve the revision against", ref)
+	}
+
+	rev, path, ok := strings.Cut(ref, ":")
+	if ok {
+		data, err := git.NewClient(&git.Options{Dir: filepath.Dir(sumFiles[0])}).Show(rev, path)
+		if err != nil {
+			return nil, errors.Errorf("%s: not a file or a git revision: %s", ref, err)
+		}
+		refOpts.SumData = [][]byte{data}
+		return ReadGoSum(&refOpts)
+	}
+
+	for _, file := range sumFiles {
+		g := git.NewClient(&git.Options{Dir: filepath.Dir(file)})
+		if !g.Exists(rev, "./"+filepath.Base(file)) {
+			continue
+		}
+
+		data, err := g.Show(rev, filepath.Base(file))
+		if err != nil {
+			return nil, errors.Errorf("%s: %s", ref, err)
+		}
+		refOpts.SumData = append(refOpts.SumData, data)
+	}
+
+	if len(refOpts.SumData) == 0 {
+		return nil, errors.Errorf("%s: not a file or a git revision with the go.sum file(s)", ref)
+	}
+	return ReadGoSum(&refOpts)
+}
+
+// Paths that ReadSumRef() needs to read, i.e. ref if it's a file and
+// otherwise the git repositories of sumFiles.
+func SumRefPaths(ref string, sumFiles []string) ([]string, error) {
+	exists, err := iofs.Exists(ref)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return []string{ref}, nil
+	}
+
+	paths := []string{}
+	for _, file := range sumFiles {
+		dir, err := filepath.Abs(filepath.Dir(file))
+		if err != nil {
+			return nil, err
+		}
+
+		root, err := findUp(dir, ".git")
+		if err != nil {
+			return nil, err
+		}
+		if root == "" {
+			root = dir
+		}
+
+		if !seq.Contains(paths, root) {
+			paths = append(paths, root)
+		}
+	}
+	return paths, nil
+}
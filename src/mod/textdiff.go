@@ -0,0 +1,218 @@
+package mod
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Edit distance at which the diff gives up and replaces every line.  The
+// trace in myersDiff() holds about maxEditDistance^2 ints, i.e. 8 MiB.
+const maxEditDistance = 1024
+
+type edit struct {
+	op   byte // ' ', '-' or '+'
+	text string
+}
+
+// Unified diff of a and b with the given number of context lines.  An
+// empty string is returned if the content is equal.
+func unifiedDiff(oldName string, newName string, a string, b string, context int) string {
+	if a == b {
+		return ""
+	}
+
+	edits := diffLines(splitLines(a), splitLines(b))
+
+	// Line numbers in a and b before each edit.
+	aLine := make([]int, len(edits)+1)
+	bLine := make([]int, len(edits)+1)
+	for i, e := range edits {
+		aLine[i+1] = aLine[i]
+		bLine[i+1] = bLine[i]
+		if e.op != '+' {
+			aLine[i+1]++
+		}
+		if e.op != '-' {
+			bLine[i+1]++
+		}
+	}
+
+	out := &strings.Builder{}
+	fmt.Fprintf(out, "--- %s\n+++ %s\n", oldName, newName)
+
+	for i := 0; i < len(edits); {
+		if edits[i].op == ' ' {
+			i++
+			continue
+		}
+
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for j := i; j < len(edits) && j <= end+2*context; j++ {
+			if edits[j].op != ' ' {
+				end = j
+			}
+		}
+		end += context + 1
+		if end > len(edits) {
+			end = len(edits)
+		}
+
+		aCount := aLine[end] - aLine[start]
+		bCount := bLine[end] - bLine[start]
+		fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(aLine[start], aCount), hunkRange(bLine[start], bCount))
+
+		for _, e := range edits[start:end] {
+			out.WriteByte(e.op)
+			out.WriteString(e.text)
+			if !strings.HasSuffix(e.text, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+
+		i = end
+	}
+
+	return out.String()
+}
+
+func hunkRange(start int, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Lines that are the same at the beginning and at the end are common for
+// source changes, so they're skipped before the actual diff.
+func diffLines(a []string, b []string) []edit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	edits := []edit{}
+	for _, line := range a[:prefix] {
+		edits = append(edits, edit{op: ' ', text: line})
+	}
+	edits = append(edits, myersDiff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		edits = append(edits, edit{op: ' ', text: line})
+	}
+	return edits
+}
+
+// Myers' O(ND) diff algorithm.
+func myersDiff(a []string, b []string) []edit {
+	n := len(a)
+	m := len(b)
+	off := n + m + 1
+	v := make([]int, 2*off+1)
+	trace := [][]int{}
+
+	for d := 0; d <= n+m; d++ {
+		if d > maxEditDistance {
+			return replaceLines(a, b)
+		}
+		trace = append(trace, append([]int{}, v[off-d:off+d+1]...))
+
+		for k := -d; k <= d; k += 2 {
+			x := 0
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[off+k] = x
+
+			if x >= n && y >= m {
+				return backtrack(a, b, trace)
+			}
+		}
+	}
+
+	return replaceLines(a, b)
+}
+
+func backtrack(a []string, b []string, trace [][]int) []edit {
+	edits := []edit{}
+	x := len(a)
+	y := len(b)
+
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		prevK := k - 1
+		if k == -d || (k != d && v[k-1+d] < v[k+1+d]) {
+			prevK = k + 1
+		}
+		prevX := v[prevK+d]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			edits = append(edits, edit{op: ' ', text: a[x-1]})
+			x--
+			y--
+		}
+
+		if x == prevX {
+			edits = append(edits, edit{op: '+', text: b[y-1]})
+			y--
+		} else {
+			edits = append(edits, edit{op: '-', text: a[x-1]})
+			x--
+		}
+	}
+
+	for x > 0 && y > 0 {
+		edits = append(edits, edit{op: ' ', text: a[x-1]})
+		x--
+		y--
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
+
+func replaceLines(a []string, b []string) []edit {
+	edits := []edit{}
+	for _, line := range a {
+		edits = append(edits, edit{op: '-', text: line})
+	}
+	for _, line := range b {
+		edits = append(edits, edit{op: '+', text: line})
+	}
+	return edits
+}
@@ -0,0 +1,141 @@
+package mod
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func renderEdits(edits []edit) string {
+	out := ""
+	for _, e := range edits {
+		out += string(e.op) + e.text
+	}
+	return out
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		a    string
+		b    string
+		want string
+	}{
+		{"", "", ""},
+		{"a\nb\n", "a\nb\n", " a\n b\n"},
+		{"", "a\n", "+a\n"},
+		{"a\n", "", "-a\n"},
+		{"a\nb\nc\n", "a\nx\nc\n", " a\n-b\n+x\n c\n"},
+		{"a\nc\n", "a\nb\nc\n", " a\n+b\n c\n"},
+		{"a\nb\nc\n", "a\nc\n", " a\n-b\n c\n"},
+		{"a\nb", "a\nb\n", " a\n-b+b\n"},
+	}
+
+	for _, test := range tests {
+		got := renderEdits(diffLines(splitLines(test.a), splitLines(test.b)))
+		if got != test.want {
+			t.Errorf("diffLines(%q, %q) = %q, want %q", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestDiffLinesMinimal(t *testing.T) {
+	a := strings.Split("a b c a b b a", " ")
+	b := strings.Split("c b a b a c", " ")
+	edits := diffLines(a, b)
+
+	changes := 0
+	oldLines := []string{}
+	newLines := []string{}
+	for _, e := range edits {
+		if e.op != ' ' {
+			changes++
+		}
+		if e.op != '+' {
+			oldLines = append(oldLines, e.text)
+		}
+		if e.op != '-' {
+			newLines = append(newLines, e.text)
+		}
+	}
+
+	if changes != 5 {
+		t.Errorf("got %d changes, want 5: %q", changes, renderEdits(edits))
+	}
+	if strings.Join(oldLines, " ") != strings.Join(a, " ") ||
+		strings.Join(newLines, " ") != strings.Join(b, " ") {
+		t.Errorf("edits don't reproduce the input: %q", renderEdits(edits))
+	}
+}
+
+func TestDiffLinesMaxEditDistance(t *testing.T) {
+	a := []string{}
+	b := []string{}
+	for i := 0; i < maxEditDistance; i++ {
+		a = append(a, fmt.Sprintf("a%d\n", i))
+		b = append(b, fmt.Sprintf("b%d\n", i))
+	}
+	a = append([]string{"same\n"}, append(a, "same\n")...)
+	b = append([]string{"same\n"}, append(b, "same\n")...)
+
+	edits := diffLines(a, b)
+	if len(edits) != 2*maxEditDistance+2 {
+		t.Fatalf("got %d edits, want %d", len(edits), 2*maxEditDistance+2)
+	}
+
+	for i, e := range edits {
+		want := byte('-')
+		switch {
+		case i == 0 || i == len(edits)-1:
+			want = ' '
+		case i > maxEditDistance:
+			want = '+'
+		}
+		if e.op != want {
+			t.Fatalf("edit %d: got %q, want %q", i, e.op, want)
+		}
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		a       string
+		b       string
+		context int
+		want    string
+	}{
+		{"a\nb\n", "a\nb\n", 3, ""},
+		{"", "a\n", 3, "--- old\n+++ new\n@@ -0,0 +1 @@\n+a\n"},
+		{"a\n", "", 3, "--- old\n+++ new\n@@ -1 +0,0 @@\n-a\n"},
+		{
+			"1\n2\n3\n4\n5\n",
+			"1\n2\nx\n4\n5\n",
+			1,
+			"--- old\n+++ new\n@@ -2,3 +2,3 @@\n 2\n-3\n+x\n 4\n",
+		},
+		{
+			"1\n2\n3\n4\n5\n",
+			"1\nx\n3\ny\n5\n",
+			1,
+			"--- old\n+++ new\n@@ -1,5 +1,5 @@\n 1\n-2\n+x\n 3\n-4\n+y\n 5\n",
+		},
+		{
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			"1\nx\n3\n4\n5\n6\n7\n8\ny\n10\n",
+			1,
+			"--- old\n+++ new\n@@ -1,3 +1,3 @@\n 1\n-2\n+x\n 3\n@@ -8,3 +8,3 @@\n 8\n-9\n+y\n 10\n",
+		},
+		{
+			"a",
+			"b",
+			3,
+			"--- old\n+++ new\n@@ -1 +1 @@\n-a\n\\ No newline at end of file\n+b\n\\ No newline at end of file\n",
+		},
+	}
+
+	for _, test := range tests {
+		got := unifiedDiff("old", "new", test.a, test.b, test.context)
+		if got != test.want {
+			t.Errorf("unifiedDiff(%q, %q, %d) = %q, want %q", test.a, test.b, test.context, got, test.want)
+		}
+	}
+}
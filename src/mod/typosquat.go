@@ -0,0 +1,204 @@
+package mod
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/illikainen/gofer/src/config"
+
+	"github.com/illikainen/go-cryptor/src/blob"
+	"github.com/illikainen/go-utils/src/seq"
+	"github.com/pkg/errors"
+	"golang.org/x/mod/module"
+)
+
+const (
+	LookalikeTypo      = "typo"
+	LookalikeHomoglyph = "homoglyph"
+)
+
+// ASCII sequences that look alike in common fonts.  Module paths can't
+// contain other characters, see validateName().
+var homoglyphs = strings.NewReplacer(
+	"rn", "m",
+	"vv", "w",
+	"cl", "d",
+	"I", "l",
+	"1", "l",
+	"0", "o",
+	"_", "-",
+)
+
+// Typosquat detects new module paths that look like trusted module paths,
+// e.g. github.com/sirupsen/logurs for github.com/sirupsen/logrus.  A
+// mistyped module path in `go get` would otherwise be signed as if it was
+// the intended module.
+type Typosquat struct {
+	Trusted     []string
+	MaxDistance int
+	Allow       []string
+	Fail        bool
+}
+
+type Lookalike struct {
+	Name    string
+	Trusted string
+	Kind    string // LookalikeTypo or LookalikeHomoglyph
+}
+
+// Trust every module path with a signed .mod file in sigPath as well as the
+// popular module paths in the policy.  The signature of the latest signed
+// .mod file of every module path is verified with the keyring since the
+// file names alone could have been written by anyone with access to
+// sigPath.
+func NewTyposquat(policy *config.TyposquatPolicy, sigPath string, keyring *blob.Keyring) (*Typosquat, error) {
+	trusted, err := signedNames(sigPath, keyring)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range policy.Popular {
+		_, err := validateName(name)
+		if err != nil {
+			return nil, err
+		}
+		trusted = append(trusted, name)
+	}
+
+	distance := policy.MaxDistance
+	if distance <= 0 {
+		distance = 1
+	}
+
+	return &Typosquat{
+		Trusted:     seq.Uniq(trusted),
+		MaxDistance: distance,
+		Allow:       policy.Allow,
+		Fail:        policy.Fail,
+	}, nil
+}
+
+func signedNames(sigPath string, keyring *blob.Keyring) ([]string, error) {
+	versions, err := signedVersions(sigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for name, v := range versions {
+		m := &ModFile{Name: name, Version: v[len(v)-1], sigPath: sigPath}
+		err := m.ParseSigned(keyring)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Compare every module path in the go.sum file(s) that isn't trusted with
+// the trusted module paths.  Lookalikes are logged, and an error is
+// returned if the policy is to fail.
+func (t *Typosquat) Check(s *SumFile) ([]*Lookalike, error) {
+	names := []string{}
+	for _, m := range s.ModFiles {
+		names = append(names, m.Name)
+	}
+	names = seq.Uniq(names)
+	sort.Strings(names)
+
+	lookalikes := []*Lookalike{}
+	for _, name := range names {
+		if seq.Contains(t.Trusted, name) || seq.Contains(t.Allow, name) {
+			continue
+		}
+
+		for _, trusted := range t.Trusted {
+			kind := t.lookalike(name, trusted)
+			if kind == "" {
+				continue
+			}
+
+			lookalikes = append(lookalikes, &Lookalike{Name: name, Trusted: trusted, Kind: kind})
+			if t.Fail {
+				s.log.Errorf("%s: %s of trusted module %s", name, kind, trusted)
+			} else {
+				s.log.Warnf("%s: %s of trusted module %s", name, kind, trusted)
+			}
+		}
+	}
+
+	if t.Fail && len(lookalikes) > 0 {
+		return nil, errors.Errorf("%d module path(s) look like trusted module paths", len(lookalikes))
+	}
+	return lookalikes, nil
+}
+
+// Kind of lookalike that name is of trusted, or an empty string if it
+// isn't one.  Other major versions of trusted aren't lookalikes, e.g.
+// gopkg.in/yaml.v3 for gopkg.in/yaml.v2.
+func (t *Typosquat) lookalike(name string, trusted string) string {
+	name = trimMajor(name)
+	trusted = trimMajor(trusted)
+
+	switch {
+	case name == trusted:
+		return ""
+	case homoglyphs.Replace(name) == homoglyphs.Replace(trusted) || strings.EqualFold(name, trusted):
+		return LookalikeHomoglyph
+	case editDistance(name, trusted, t.MaxDistance) <= t.MaxDistance:
+		return LookalikeTypo
+	default:
+		return ""
+	}
+}
+
+func trimMajor(name string) string {
+	prefix, _, ok := module.SplitPathVersion(name)
+	if !ok {
+		return name
+	}
+	return prefix
+}
+
+// Optimal string alignment distance between a and b, i.e. the Levenshtein
+// distance with transpositions of adjacent characters.  The computation
+// stops early once the distance exceeds limit.
+func editDistance(a string, b string, limit int) int {
+	if len(a)-len(b) > limit || len(b)-len(a) > limit {
+		return limit + 1
+	}
+
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		lowest := cur[0]
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			cur[j] = minInt(minInt(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = minInt(cur[j], prev2[j-2]+1)
+			}
+			lowest = minInt(lowest, cur[j])
+		}
+
+		if lowest > limit {
+			return limit + 1
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+
+	return prev[len(b)]
+}
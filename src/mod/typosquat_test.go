@@ -0,0 +1,33 @@
+package mod
+
+import (
+	"testing"
+)
+
+func TestLookalike(t *testing.T) {
+	typosquat := &Typosquat{MaxDistance: 1}
+	tests := []struct {
+		name    string
+		trusted string
+		want    string
+	}{
+		{"github.com/sirupsn/logrus", "github.com/sirupsen/logrus", LookalikeTypo},
+		{"githib.com/sirupsen/logrus", "github.com/sirupsen/logrus", LookalikeTypo},
+		{"github.com/Sirupsen/logrus", "github.com/sirupsen/logrus", LookalikeHomoglyph},
+		{"github.com/rnattn/go-isatty", "github.com/mattn/go-isatty", LookalikeHomoglyph},
+		{"github.com/sirupsen/logurs", "github.com/sirupsen/logrus", LookalikeTypo},
+		{"github.com/sirupsen/logurs/v2", "github.com/sirupsen/logrus", LookalikeTypo},
+		{"github.com/foo/bar/v3", "github.com/foo/bar/v2", ""},
+		{"github.com/foo/bar/v2", "github.com/foo/bar", ""},
+		{"gopkg.in/yaml.v3", "gopkg.in/yaml.v2", ""},
+		{"gopkg.in/yml.v2", "gopkg.in/yaml.v2", LookalikeTypo},
+		{"github.com/spf13/cobra", "github.com/sirupsen/logrus", ""},
+	}
+
+	for _, test := range tests {
+		got := typosquat.lookalike(test.name, test.trusted)
+		if got != test.want {
+			t.Errorf("lookalike(%q, %q) = %q, want %q", test.name, test.trusted, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,336 @@
+package mod
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/illikainen/go-netutils/src/transport"
+	"github.com/illikainen/go-utils/src/errorx"
+	"github.com/illikainen/go-utils/src/iofs"
+	"github.com/illikainen/go-utils/src/seq"
+	"github.com/pkg/errors"
+	"golang.org/x/mod/module"
+	modzip "golang.org/x/mod/zip"
+	"golang.org/x/sync/errgroup"
+)
+
+// Largest .mod and .info files that are accepted from a proxy.
+const (
+	maxUpstreamMod  = 16 << 20
+	maxUpstreamInfo = 1 << 20
+)
+
+// Proxy is a module proxy that speaks the GOPROXY protocol, see
+// https://go.dev/ref/mod#goproxy-protocol.  Connections are refused to
+// every host except the proxy host, including redirects.
+type Proxy struct {
+	URL    *url.URL
+	Client *http.Client
+}
+
+func NewProxy(uri string) (*Proxy, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.Errorf("invalid proxy: %s", uri)
+	}
+
+	allowed := hostPort(u)
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.Proxy = nil
+	tr.DialContext = func(ctx context.Context, network string, addr string) (net.Conn, error) {
+		if addr != allowed {
+			return nil, errors.Errorf("refusing to connect to %s, only %s is allowed", addr, allowed)
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	return &Proxy{
+		URL: u,
+		Client: &http.Client{
+			Transport: tr,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if hostPort(req.URL) != allowed {
+					return errors.Errorf("refusing redirect to %s", req.URL)
+				}
+				if len(via) >= 10 {
+					return errors.Errorf("too many redirects")
+				}
+				return nil
+			},
+		},
+	}, nil
+}
+
+// URL of the checksum database when it's proxied through this proxy.
+func (p *Proxy) SumDBURL(name string) string {
+	return p.URL.JoinPath("sumdb", name).String()
+}
+
+func (p *Proxy) versionURL(name string, version string, ext string) (string, error) {
+	escName, err := module.EscapePath(name)
+	if err != nil {
+		return "", err
+	}
+
+	escVersion, err := module.EscapeVersion(version)
+	if err != nil {
+		return "", err
+	}
+
+	return p.URL.JoinPath(escName, "@v", escVersion+ext).String(), nil
+}
+
+// Download uri and verify it with verify while it's being read.  The
+// content is only moved into dst once it has been verified.
+func (p *Proxy) download(uri string, dst string, limit int64, verify streamVerifier) (err error) {
+	resp, err := p.Client.Get(uri)
+	if err != nil {
+		return err
+	}
+	defer errorx.Defer(resp.Body.Close, &err)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusGone:
+		return errors.Wrap(transport.ErrNotExist, uri)
+	default:
+		return errors.Errorf("%s: %s", uri, resp.Status)
+	}
+
+	staged, err := stageFile(dst)
+	if err != nil {
+		return err
+	}
+	defer errorx.Defer(staged.Close, &err)
+
+	body := &limitedReader{r: resp.Body, n: limit}
+	tee := io.TeeReader(body, staged.File)
+	err = verify(tee, staged.File)
+	if err != nil {
+		return errors.Wrap(err, uri)
+	}
+
+	n, err := io.Copy(io.Discard, tee)
+	if err != nil {
+		return err
+	}
+	if n != 0 {
+		return errors.Errorf("%s: trailing data", uri)
+	}
+
+	return staged.Commit()
+}
+
+// Like io.LimitReader but oversized content is an error rather than EOF.
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(b []byte) (int, error) {
+	n, err := l.r.Read(b)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return 0, errors.Errorf("response too large")
+	}
+	return n, err
+}
+
+type FetchResult struct {
+	ModFiles  []string
+	InfoFiles []string
+	Sources   []string
+	Existing  []string // files that were already in GOPATH and verified
+}
+
+// Download the .mod, .info and .zip files for the go.sum file(s) from an
+// upstream proxy.  Every .mod and .zip file is checked against go.sum and
+// the checksum database, if any, and every .info file is validated before
+// it's written to GOPATH.  Files that already exist are verified instead,
+// against the checksum database as well since go.sum alone could have
+// been written together with the files.
+func (s *SumFile) FetchUpstream(proxy *Proxy, db *SumDB) (*FetchResult, error) {
+	result := &FetchResult{}
+	group := errgroup.Group{}
+	semaphore := make(chan int, 3)
+	mu := sync.Mutex{}
+
+	record := func(files *[]string, file string) {
+		mu.Lock()
+		defer mu.Unlock()
+		*files = append(*files, file)
+	}
+
+	for _, m := range s.ModFiles {
+		m := m
+		group.Go(func() error {
+			semaphore <- 1
+			defer func() { <-semaphore }()
+
+			if db != nil {
+				err := db.Check(m.Name, m.Version, true, m.Checksum)
+				if err != nil {
+					return err
+				}
+			}
+
+			exists, err := iofs.Exists(m.ModPath())
+			if err != nil {
+				return err
+			}
+			if exists {
+				record(&result.Existing, m.ModPath())
+				return m.Verify(m.ModPath())
+			}
+
+			uri, err := proxy.versionURL(m.Name, m.Version, ".mod")
+			if err != nil {
+				return err
+			}
+
+			s.log.Infof("%s: download from %s", m, uri)
+			err = proxy.download(uri, m.ModPath(), maxUpstreamMod, m.verifyStream)
+			if err != nil {
+				return err
+			}
+			record(&result.ModFiles, m.ModPath())
+			return nil
+		})
+	}
+
+	err := group.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := []string{}
+	for _, m := range s.ModFiles {
+		for _, i := range m.InfoFiles {
+			if seq.Contains(seen, i.String()) {
+				continue
+			}
+			seen = append(seen, i.String())
+			i := i
+
+			group.Go(func() error {
+				semaphore <- 1
+				defer func() { <-semaphore }()
+
+				verify := func(r io.Reader, staged *os.File) error {
+					err := i.verifyStream(r, staged)
+					if err != nil {
+						return err
+					}
+					if i.Info.Version != i.Version {
+						return errors.Errorf("%s: version mismatch: %s", i, i.Info.Version)
+					}
+					return nil
+				}
+
+				exists, err := iofs.Exists(i.InfoPath())
+				if err != nil {
+					return err
+				}
+				if exists {
+					record(&result.Existing, i.InfoPath())
+					return i.Verify(i.InfoPath())
+				}
+
+				uri, err := proxy.versionURL(i.Name, i.Version, ".info")
+				if err != nil {
+					return err
+				}
+
+				s.log.Infof("%s: download from %s", i, uri)
+				err = proxy.download(uri, i.InfoPath(), maxUpstreamInfo, verify)
+				if errors.Is(err, transport.ErrNotExist) {
+					s.log.Debugf("%s: not available", i)
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				record(&result.InfoFiles, i.InfoPath())
+				return nil
+			})
+		}
+	}
+
+	err = group.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, src := range s.Sources {
+		src := src
+		group.Go(func() error {
+			semaphore <- 1
+			defer func() { <-semaphore }()
+
+			if db != nil {
+				err := db.Check(src.Name, src.Version, false, src.Checksum)
+				if err != nil {
+					return err
+				}
+			}
+
+			exists, err := iofs.Exists(src.ZipPath())
+			if err != nil {
+				return err
+			}
+			if exists {
+				record(&result.Existing, src.ZipPath())
+				return src.Verify(src.ZipPath(), ZipMode)
+			}
+
+			uri, err := proxy.versionURL(src.Name, src.Version, ".zip")
+			if err != nil {
+				return err
+			}
+
+			s.log.Infof("%s: download from %s", src, uri)
+			err = proxy.download(uri, src.ZipPath(), modzip.MaxZipFile, src.verifyStream)
+			if err != nil {
+				return err
+			}
+
+			err = writeIfNotExists(src.ZipHashPath(), []byte(src.Checksum))
+			if err != nil {
+				return err
+			}
+
+			err = src.Verify(src.ZipPath(), ZipMode)
+			if err != nil {
+				return err
+			}
+			record(&result.Sources, src.ZipPath())
+			return nil
+		})
+	}
+
+	err = group.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func hostPort(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[u.Scheme]
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
@@ -0,0 +1,256 @@
+package mod
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/illikainen/gofer/src/h1"
+
+	"github.com/illikainen/go-utils/src/iofs"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/sumdb"
+	"golang.org/x/mod/sumdb/note"
+	modzip "golang.org/x/mod/zip"
+)
+
+type testModule struct {
+	Name    string
+	Version string
+	Mod     []byte
+	Zip     []byte
+	ModSum  string
+	ZipSum  string
+}
+
+func newTestModule(t *testing.T, name string, version string, content string) *testModule {
+	t.Helper()
+
+	m := &testModule{Name: name, Version: version, Mod: []byte(fmt.Sprintf("module %s\n\ngo 1.19\n", name))}
+
+	dir := t.TempDir()
+	for file, data := range map[string][]byte{"go.mod": m.Mod, "m.go": []byte("package m\n\n" + content)} {
+		err := os.WriteFile(filepath.Join(dir, file), data, 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	err := modzip.CreateFromDir(buf, module.Version{Path: name, Version: version}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Zip = buf.Bytes()
+
+	zipPath := filepath.Join(t.TempDir(), "m.zip")
+	err = os.WriteFile(zipPath, m.Zip, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.ZipSum, err = h1.HashZip(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.ModSum, err = h1.HashModData(m.Mod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func (m *testModule) GoSum() []byte {
+	return []byte(fmt.Sprintf("%s %s %s\n%s %s/go.mod %s\n",
+		m.Name, m.Version, m.ZipSum, m.Name, m.Version, m.ModSum))
+}
+
+// Serve the .mod and .zip files of m like a proxy, every other file is
+// missing.
+func (m *testModule) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case fmt.Sprintf("/%s/@v/%s.mod", m.Name, m.Version):
+		_, _ = w.Write(m.Mod)
+	case fmt.Sprintf("/%s/@v/%s.zip", m.Name, m.Version):
+		_, _ = w.Write(m.Zip)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newTestSumFile(t *testing.T, goPath string, m *testModule) *SumFile {
+	t.Helper()
+
+	sum, err := ReadGoSum(&SumOptions{SumData: [][]byte{m.GoSum()}, SigPath: t.TempDir(), GoPath: goPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sum
+}
+
+func newTestProxy(t *testing.T, handler http.Handler) *Proxy {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	proxy, err := NewProxy(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return proxy
+}
+
+// Serve a checksum database with the go.sum lines of modules at
+// /sumdb/<name>/ of the returned handler, and m at every other path.
+func newTestSumDBHandler(t *testing.T, name string, m http.Handler,
+	modules ...*testModule) (http.Handler, string) {
+	t.Helper()
+
+	skey, vkey, err := note.GenerateKey(rand.Reader, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ops := sumdb.NewTestServer(skey, func(path string, version string) ([]byte, error) {
+		for _, m := range modules {
+			if m.Name == path && m.Version == version {
+				return m.GoSum(), nil
+			}
+		}
+		return nil, os.ErrNotExist
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("/sumdb/"+name+"/", http.StripPrefix("/sumdb/"+name, sumdb.NewServer(ops)))
+	mux.Handle("/", m)
+	return mux, vkey
+}
+
+func newTestSumDB(t *testing.T, proxy *Proxy, name string, vkey string) *SumDB {
+	t.Helper()
+
+	db, err := NewSumDB(&SumDBOptions{
+		Key:      vkey,
+		URL:      proxy.SumDBURL(name),
+		Client:   proxy.Client,
+		CacheDir: t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestFetchUpstream(t *testing.T) {
+	m := newTestModule(t, "example.com/m", "v1.0.0", "")
+	goPath := t.TempDir()
+	sum := newTestSumFile(t, goPath, m)
+	proxy := newTestProxy(t, m)
+
+	result, err := sum.FetchUpstream(proxy, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.ModFiles) != 1 || len(result.Sources) != 1 || len(result.Existing) != 0 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	data, err := iofs.ReadFile(sum.ModFiles[0].ModPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, m.Mod) {
+		t.Fatalf("%s: unexpected content: %q", sum.ModFiles[0].ModPath(), data)
+	}
+
+	result, err = sum.FetchUpstream(proxy, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.ModFiles) != 0 || len(result.Sources) != 0 || len(result.Existing) != 2 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+}
+
+func TestFetchUpstreamChecksumMismatch(t *testing.T) {
+	m := newTestModule(t, "example.com/m", "v1.0.0", "")
+	other := newTestModule(t, "example.com/m", "v1.0.0", "var X = 1\n")
+	goPath := t.TempDir()
+	sum := newTestSumFile(t, goPath, m)
+	proxy := newTestProxy(t, other)
+
+	_, err := sum.FetchUpstream(proxy, nil)
+	if err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Fatalf("expected a checksum mismatch, got %v", err)
+	}
+
+	exists, err := iofs.Exists(sum.Sources[0].ZipPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists {
+		t.Fatalf("%s: written despite the mismatch", sum.Sources[0].ZipPath())
+	}
+}
+
+func TestFetchUpstreamRedirect(t *testing.T) {
+	m := newTestModule(t, "example.com/m", "v1.0.0", "")
+	goPath := t.TempDir()
+	sum := newTestSumFile(t, goPath, m)
+
+	other := httptest.NewServer(m)
+	t.Cleanup(other.Close)
+
+	proxy := newTestProxy(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, other.URL+r.URL.Path, http.StatusFound)
+	}))
+
+	_, err := sum.FetchUpstream(proxy, nil)
+	if err == nil || !strings.Contains(err.Error(), "refusing redirect") {
+		t.Fatalf("expected a refused redirect, got %v", err)
+	}
+}
+
+func TestFetchUpstreamOversized(t *testing.T) {
+	m := newTestModule(t, "example.com/m", "v1.0.0", "")
+	m.Mod = append(m.Mod, bytes.Repeat([]byte("\n"), maxUpstreamMod)...)
+	goPath := t.TempDir()
+	sum := newTestSumFile(t, goPath, m)
+	proxy := newTestProxy(t, m)
+
+	_, err := sum.FetchUpstream(proxy, nil)
+	if err == nil || !strings.Contains(err.Error(), "response too large") {
+		t.Fatalf("expected an oversized response, got %v", err)
+	}
+}
+
+func TestFetchUpstreamSumDB(t *testing.T) {
+	m := newTestModule(t, "example.com/m", "v1.0.0", "")
+	goPath := t.TempDir()
+	sum := newTestSumFile(t, goPath, m)
+
+	handler, vkey := newTestSumDBHandler(t, "sum.example.com", m, m)
+	proxy := newTestProxy(t, handler)
+	_, err := sum.FetchUpstream(proxy, newTestSumDB(t, proxy, "sum.example.com", vkey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The files are already in GOPATH and match go.sum, but the checksum
+	// database disagrees.
+	other := newTestModule(t, "example.com/m", "v1.0.0", "var X = 1\n")
+	handler, vkey = newTestSumDBHandler(t, "sum.example.com", m, other)
+	proxy = newTestProxy(t, handler)
+	_, err = sum.FetchUpstream(proxy, newTestSumDB(t, proxy, "sum.example.com", vkey))
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected a checksum mismatch, got %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package mod
 
 import (
+	"path/filepath"
 	"regexp"
 	"strings"
 
@@ -16,6 +17,12 @@ func downcase(str string) string {
 	})
 }
 
+// Directory where Go caches downloaded files for a module.  It's laid out
+// according to the GOPROXY protocol.
+func downloadDir(goPath string, name string) string {
+	return filepath.Join(goPath, "pkg", "mod", "cache", "download", downcase(name))
+}
+
 func validateName(name string) (string, error) {
 	matched, err := regexp.MatchString(`^[a-z][a-zA-Z0-9/._-]+$`, name)
 	if err != nil {
@@ -0,0 +1,388 @@
+package mod
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/illikainen/go-utils/src/errorx"
+	"github.com/illikainen/go-utils/src/fn"
+	"github.com/illikainen/go-utils/src/iofs"
+	"github.com/illikainen/go-utils/src/seq"
+	"github.com/pkg/errors"
+	"golang.org/x/mod/semver"
+)
+
+// Subset of the OSV schema used by the Go vulnerability database, see
+// https://ossf.github.io/osv-schema/ and https://go.dev/security/vuln/database.
+type OSVEntry struct {
+	ID        string
+	Aliases   []string
+	Summary   string
+	Details   string
+	Withdrawn string
+	Affected  []*OSVAffected
+}
+
+type OSVAffected struct {
+	Package struct {
+		Name      string
+		Ecosystem string
+	}
+	Ranges            []*OSVRange
+	EcosystemSpecific struct {
+		Imports []*OSVImport
+	} `json:"ecosystem_specific"`
+}
+
+type OSVRange struct {
+	Type   string
+	Events []struct {
+		Introduced string
+		Fixed      string
+	}
+}
+
+type OSVImport struct {
+	Path    string
+	Symbols []string
+}
+
+type Vuln struct {
+	Name       string
+	Version    string
+	ID         string
+	Aliases    []string `json:",omitempty"`
+	Summary    string
+	Fixed      string   // lowest fixed version above Version, empty if there's no fix
+	Signed     bool     // a signed blob exists for the fixed version
+	Imports    []string `json:",omitempty"` // affected packages
+	References []string `json:",omitempty"` // uses of affected symbols, see FindReferences()
+	imports    []*OSVImport
+}
+
+func (v *Vuln) String() string {
+	fixed := "no fixed version"
+	if v.Fixed != "" {
+		fixed = fmt.Sprintf("fixed in %s", v.Fixed)
+		if v.Signed {
+			fixed += " (signed)"
+		}
+	}
+
+	id := v.ID
+	if len(v.Aliases) > 0 {
+		id = fmt.Sprintf("%s (%s)", v.ID, strings.Join(v.Aliases, ", "))
+	}
+	return fmt.Sprintf("%s@%s: %s: %s, %s", v.Name, v.Version, id, v.Summary, fixed)
+}
+
+// Read every OSV entry in a local copy of the Go vulnerability database.
+// The database may be a directory or a zip file.  Index files and files
+// that aren't OSV entries are ignored.
+func ReadVulnDB(dbPath string) (entries []*OSVEntry, err error) {
+	info, err := os.Stat(dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var fsys fs.FS
+	if info.IsDir() {
+		fsys = os.DirFS(dbPath)
+	} else {
+		z, err := zip.OpenReader(dbPath)
+		if err != nil {
+			return nil, err
+		}
+		defer errorx.Defer(z.Close, &err)
+		fsys = z
+	}
+
+	entries = []*OSVEntry{}
+	err = fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || path.Ext(name) != ".json" {
+			return nil
+		}
+
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		data = bytes.TrimSpace(data)
+
+		// Legacy databases store an array of entries for each module.
+		found := []*OSVEntry{}
+		if bytes.HasPrefix(data, []byte("[")) {
+			err = json.Unmarshal(data, &found)
+		} else {
+			e := &OSVEntry{}
+			err = json.Unmarshal(data, e)
+			found = append(found, e)
+		}
+		if err != nil {
+			return errors.Errorf("%s: %s", name, err)
+		}
+
+		for _, e := range found {
+			if e.ID != "" && len(e.Affected) > 0 {
+				entries = append(entries, e)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(entries) == 0 {
+		return nil, errors.Errorf("%s: no OSV entries", dbPath)
+	}
+
+	sort.Slice(entries, func(i int, j int) bool {
+		return entries[i].ID < entries[j].ID
+	})
+	return entries, nil
+}
+
+// Match the OSV entries against every module version in the go.sum
+// file(s).  Withdrawn entries are ignored.
+func (s *SumFile) Vulns(entries []*OSVEntry) ([]*Vuln, error) {
+	vulns := []*Vuln{}
+	seen := []string{}
+
+	for _, src := range s.Sources {
+		if seq.Contains(seen, src.String()) {
+			continue
+		}
+		seen = append(seen, src.String())
+
+		for _, e := range entries {
+			if e.Withdrawn != "" {
+				continue
+			}
+
+			for _, affected := range e.Affected {
+				if affected.Package.Name != src.Name || affected.Package.Ecosystem != "Go" {
+					continue
+				}
+
+				hit, fixed, err := affected.matches(src.Version)
+				if err != nil {
+					return nil, errors.Errorf("%s: %s", e.ID, err)
+				}
+				if !hit {
+					continue
+				}
+
+				v := &Vuln{
+					Name:    src.Name,
+					Version: src.Version,
+					ID:      e.ID,
+					Aliases: e.Aliases,
+					Summary: e.Summary,
+					Fixed:   fixed,
+				}
+				for _, imp := range affected.EcosystemSpecific.Imports {
+					v.Imports = append(v.Imports, imp.Path)
+					v.imports = append(v.imports, imp)
+				}
+
+				if fixed != "" {
+					fixedSrc := &Source{Name: src.Name, Version: fixed, sigPath: s.sigPath}
+					v.Signed, err = iofs.Exists(fixedSrc.SigPath())
+					if err != nil {
+						return nil, err
+					}
+				}
+
+				vulns = append(vulns, v)
+			}
+		}
+	}
+
+	return vulns, nil
+}
+
+// Whether the version is in one of the SEMVER ranges, and the lowest fixed
+// version above it.
+func (a *OSVAffected) matches(version string) (bool, string, error) {
+	affected := false
+	fixed := ""
+
+	for _, r := range a.Ranges {
+		if r.Type != "SEMVER" {
+			continue
+		}
+
+		inRange := false
+		for _, event := range r.Events {
+			switch {
+			case event.Introduced != "":
+				intro := osvVersion(event.Introduced)
+				if !semver.IsValid(intro) {
+					return false, "", errors.Errorf("invalid version: %s", event.Introduced)
+				}
+				if semver.Compare(version, intro) >= 0 {
+					inRange = true
+				}
+			case event.Fixed != "":
+				fix := osvVersion(event.Fixed)
+				if !semver.IsValid(fix) {
+					return false, "", errors.Errorf("invalid version: %s", event.Fixed)
+				}
+				if semver.Compare(version, fix) >= 0 {
+					inRange = false
+				} else if inRange && (fixed == "" || semver.Compare(fix, fixed) < 0) {
+					fixed = fix
+				}
+			}
+		}
+
+		affected = affected || inRange
+	}
+
+	return affected, fn.Ternary(affected, fixed, ""), nil
+}
+
+func osvVersion(version string) string {
+	if version == "0" {
+		return "v0.0.0-0"
+	}
+	return "v" + version
+}
+
+// Find uses of the affected symbols in the Go files in dirs.  Files that
+// import an affected package are reported if the vulnerability doesn't
+// list any symbols.  Methods are matched by name only since type
+// information isn't available.
+func (v *Vuln) FindReferences(dirs []string) error {
+	if len(v.imports) == 0 {
+		return nil
+	}
+
+	fset := token.NewFileSet()
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(name string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			base := filepath.Base(name)
+			if entry.IsDir() {
+				if name != dir && (base == "testdata" || base == "vendor" || strings.HasPrefix(base, ".")) {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if filepath.Ext(name) != ".go" || strings.HasSuffix(name, "_test.go") {
+				return nil
+			}
+
+			if file := parseGoFile(fset, name); file != nil {
+				v.References = append(v.References, fileReferences(fset, file, v.imports)...)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	v.References = seq.Uniq(v.References)
+	return nil
+}
+
+// Parse a Go file, or return nil if it can't be parsed.  Files that can't
+// be parsed can't be compiled either, so they can't reference anything.
+func parseGoFile(fset *token.FileSet, name string) *ast.File {
+	file, err := parser.ParseFile(fset, name, nil, parser.SkipObjectResolution)
+	if err != nil {
+		return nil
+	}
+	return file
+}
+
+func fileReferences(fset *token.FileSet, file *ast.File, imports []*OSVImport) []string {
+	refs := []string{}
+
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+
+		imp, ok := seq.FindBy(imports, func(i *OSVImport) bool { return i.Path == importPath })
+		if !ok {
+			continue
+		}
+
+		if len(imp.Symbols) == 0 {
+			refs = append(refs, fmt.Sprintf("%s: imports %s", fset.Position(spec.Pos()), importPath))
+			continue
+		}
+
+		name := assumedPackageName(importPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+
+		funcs := []string{}
+		methods := []string{}
+		for _, symbol := range imp.Symbols {
+			if _, method, ok := strings.Cut(symbol, "."); ok {
+				methods = append(methods, method)
+			} else {
+				funcs = append(funcs, symbol)
+			}
+		}
+
+		ast.Inspect(file, func(node ast.Node) bool {
+			sel, ok := node.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+
+			x, isIdent := sel.X.(*ast.Ident)
+			if (isIdent && x.Name == name && seq.Contains(funcs, sel.Sel.Name)) ||
+				seq.Contains(methods, sel.Sel.Name) {
+				refs = append(refs, fmt.Sprintf("%s: %s.%s", fset.Position(sel.Pos()), importPath, sel.Sel.Name))
+			}
+			return true
+		})
+	}
+
+	return refs
+}
+
+// Package name that is assumed for an import path without a name, the same
+// way as goimports does it.
+func assumedPackageName(importPath string) string {
+	base := path.Base(importPath)
+	if majorSuffix.MatchString(importPath) {
+		base = path.Base(path.Dir(importPath))
+	}
+	base = strings.TrimPrefix(base, "go-")
+
+	i := strings.IndexFunc(base, func(r rune) bool {
+		return !(r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r))
+	})
+	if i >= 0 {
+		base = base[:i]
+	}
+	return base
+}
@@ -1,8 +1,12 @@
 package mod
 
 import (
+	"path/filepath"
+
 	"github.com/illikainen/go-utils/src/iofs"
+	"github.com/illikainen/go-utils/src/seq"
 	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
 )
 
 func ParseWork(file string) (*modfile.WorkFile, error) {
@@ -13,3 +17,132 @@ func ParseWork(file string) (*modfile.WorkFile, error) {
 
 	return modfile.ParseWork(file, data, nil)
 }
+
+type Workspace struct {
+	Root     string           // e.g. $HOME/src/project
+	SumFiles []string         // go.sum for every used module and go.work.sum
+	Dirs     []string         // directories with used and replacement modules
+	Local    []module.Version // modules provided by local directories, an empty version matches all
+}
+
+// Read the go.work file in root and collect the checksum files that are
+// consulted by the Go command in workspace mode.  Modules that are used by
+// the workspace or replaced by a local directory are trusted local code,
+// and they're recorded so that they aren't treated as downloads.
+func ReadWorkspace(root string) (*Workspace, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	work, err := ParseWork(filepath.Join(root, "go.work"))
+	if err != nil {
+		return nil, err
+	}
+
+	ws := &Workspace{Root: root}
+	for _, use := range work.Use {
+		dir := localPath(root, use.Path)
+		mod, err := ParseMod(filepath.Join(dir, "go.mod"))
+		if err != nil {
+			return nil, err
+		}
+
+		ws.addDir(dir, module.Version{Path: mod.Module.Mod.Path})
+
+		err = ws.addSumFile(filepath.Join(dir, "go.sum"))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, replace := range mod.Replace {
+			if isLocalReplace(replace) {
+				ws.addDir(localPath(dir, replace.New.Path), replace.Old)
+			}
+		}
+	}
+
+	for _, replace := range work.Replace {
+		if isLocalReplace(replace) {
+			ws.addDir(localPath(root, replace.New.Path), replace.Old)
+		}
+	}
+
+	err = ws.addSumFile(filepath.Join(root, "go.work.sum"))
+	if err != nil {
+		return nil, err
+	}
+
+	return ws, nil
+}
+
+// Paths that must be readable to process the workspace.
+func (w *Workspace) Paths() []string {
+	paths := append(append([]string{w.Root}, w.Dirs...), w.SumFiles...)
+	return seq.Uniq(seq.Filter(paths, ""))
+}
+
+func (w *Workspace) merge(other *Workspace) {
+	if w.Root == "" {
+		w.Root = other.Root
+	} else if other.Root != "" && !seq.Contains(w.Dirs, other.Root) {
+		w.Dirs = append(w.Dirs, other.Root)
+	}
+
+	for _, dir := range other.Dirs {
+		if !seq.Contains(w.Dirs, dir) {
+			w.Dirs = append(w.Dirs, dir)
+		}
+	}
+
+	for _, file := range other.SumFiles {
+		if !seq.Contains(w.SumFiles, file) {
+			w.SumFiles = append(w.SumFiles, file)
+		}
+	}
+
+	for _, mod := range other.Local {
+		if !seq.Contains(w.Local, mod) {
+			w.Local = append(w.Local, mod)
+		}
+	}
+}
+
+func (w *Workspace) addDir(dir string, mod module.Version) {
+	if !seq.Contains(w.Dirs, dir) {
+		w.Dirs = append(w.Dirs, dir)
+	}
+
+	if !seq.Contains(w.Local, mod) {
+		w.Local = append(w.Local, mod)
+	}
+}
+
+func (w *Workspace) addSumFile(file string) error {
+	exists, err := iofs.Exists(file)
+	if err != nil {
+		return err
+	}
+
+	if exists && !seq.Contains(w.SumFiles, file) {
+		w.SumFiles = append(w.SumFiles, file)
+	}
+	return nil
+}
+
+func isLocalReplace(replace *modfile.Replace) bool {
+	return replace.New.Version == "" && modfile.IsDirectoryPath(replace.New.Path)
+}
+
+func localPath(base string, path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(base, filepath.FromSlash(path))
+}
+
+func isLocal(local []module.Version, name string, version string) bool {
+	return seq.ContainsBy(local, func(mod module.Version) bool {
+		return mod.Path == name && (mod.Version == "" || mod.Version == version)
+	})
+}